@@ -1,22 +1,40 @@
 package main
 
 import (
-	"encoding/json"
-	"io"
+	"context"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/zenha/oliveiras/internal/bot"
+	"github.com/zenha/oliveiras/internal/cli"
 	"github.com/zenha/oliveiras/internal/database"
-	"github.com/zenha/oliveiras/internal/models"
 	"github.com/zenha/oliveiras/internal/scraper"
 	"github.com/zenha/oliveiras/internal/telegram"
 	"github.com/zenha/oliveiras/pkg/config"
 )
 
-var lastUpdateID int
-
 func main() {
+	// Scraper processes start through the bot's own binary, which applies their resource
+	// limits and then runs the script
+	if len(os.Args) > 1 && os.Args[1] == scraper.SandboxExecCommand {
+		if err := scraper.RunSandboxExec(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// Offline tools that need neither configuration nor database
+	if len(os.Args) > 1 && os.Args[1] == "parse" {
+		if err := runParse(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
@@ -30,46 +48,83 @@ func main() {
 	}
 	defer mongoClient.Disconnect()
 
-	// Initialize services
-	telegramClient := telegram.NewClient(cfg.TelegramToken)
-	scraperService := scraper.NewService(cfg.PythonPath, cfg.ScraperPath)
-	botHandler := bot.NewHandler(telegramClient, scraperService)
-
-	// Setup webhook handler
-	http.HandleFunc("/webhook", func(w http.ResponseWriter, r *http.Request) {
-		body, err := io.ReadAll(r.Body)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
+	archive := scraper.NewArchive(cfg.ArchiveDir)
+	if len(os.Args) > 1 && os.Args[1] == "reprocess" {
+		if err := runReprocess(os.Args[2:], mongoClient, archive); err != nil {
+			log.Fatal(err)
 		}
-		log.Println(string(body) + "\n")
+		return
+	}
 
-		var update models.TelegramUpdate
-		if err := json.Unmarshal(body, &update); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
-			return
-		}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-		// Check if we've already processed this update
-		if update.UpdateID <= lastUpdateID {
-			w.WriteHeader(http.StatusOK)
-			return
+	// Pick the transport: "chat" runs a local terminal REPL, anything else serves the Telegram webhook
+	var transport bot.Transport
+	if len(os.Args) > 1 && os.Args[1] == "chat" {
+		workDir, err := os.Getwd()
+		if err != nil {
+			log.Fatal("Failed to get working directory:", err)
 		}
-
-		// Update the last processed update ID
-		lastUpdateID = update.UpdateID
-
-		if err := botHandler.HandleMessage(update.Message.Chat.ID, update.Message.Text); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
+		transport = cli.NewREPL(os.Stdin, os.Stdout, workDir)
+	} else {
+		// Runs still marked running were cut short when the bot last stopped. The local REPL
+		// may run beside the bot, so only the bot itself cleans them up.
+		if marked, err := mongoClient.MarkInterruptedRuns(); err != nil {
+			log.Println("Failed to mark interrupted scrape runs:", err)
+		} else if marked > 0 {
+			log.Printf("Marked %d scrape runs interrupted by the last shutdown\n", marked)
 		}
+		transport = telegram.NewWebhook(telegram.NewClient(cfg.TelegramToken), cfg.ServerPort)
+	}
 
-		w.WriteHeader(http.StatusOK)
+	// Initialize services
+	sandbox := &scraper.Sandbox{
+		Limits: scraper.Limits{
+			MemoryBytes: uint64(cfg.ScraperMaxMemoryMB) * 1024 * 1024,
+			CPUSeconds:  uint64(cfg.ScraperMaxCPU.Seconds()),
+			OpenFiles:   uint64(cfg.ScraperMaxFiles),
+			Processes:   uint64(cfg.ScraperMaxProcesses),
+		},
+		WorkDir: cfg.ScraperWorkDir,
+		Env:     cfg.ScraperEnv,
+	}
+	var script interface {
+		Provider(platform string) scraper.Provider
+	}
+	if cfg.ScraperWorkers > 0 {
+		pool := scraper.NewWorkerPool(cfg.PythonPath, cfg.ScraperPath, cfg.ScraperWorkers, cfg.ScraperTimeout, sandbox)
+		defer pool.Close()
+		script = pool
+	} else {
+		script = scraper.NewScript(cfg.PythonPath, cfg.ScraperPath, cfg.ScraperTimeout, sandbox)
+	}
+	// Native platforms fetch their search page and parse it in Go instead of running the script
+	pageClient := &http.Client{Timeout: cfg.ScraperTimeout}
+	providers := []scraper.Provider{}
+	for _, platform := range []string{scraper.PlatformAirbnb, scraper.PlatformBooking, scraper.PlatformVrbo} {
+		provider := script.Provider(platform)
+		if cfg.IsNativePlatform(platform) {
+			provider, err = scraper.NewNativeProvider(platform, pageClient)
+			if err != nil {
+				log.Fatal("Invalid NATIVE_PLATFORMS: ", err)
+			}
+		}
+		providers = append(providers, provider)
+	}
+	scraperService := scraper.NewService(scraper.NewRegistry(providers...), mongoClient, scraper.RetryPolicy{
+		Attempts:  cfg.ScraperAttempts,
+		BaseDelay: cfg.ScraperRetryDelay,
+		MaxDelay:  5 * time.Minute,
 	})
+	scraperService.SetCacheAge(cfg.ScrapeCacheAge)
+	scraperService.SetArchive(archive)
+	scheduler := scraper.NewScheduler(scraperService, cfg.ScraperConcurrency, cfg.PlatformConcurrency)
+	botHandler := bot.NewHandler(transport, scheduler, mongoClient, cfg)
+	scraperService.SetAnomalyHandler(botHandler.AlertAnomaly)
+	go botHandler.RunStartupSelfTest(ctx, cfg.SelfTestLive)
 
-	// Start the server
-	log.Printf("Starting server on port %s...\n", cfg.ServerPort)
-	if err := http.ListenAndServe(":"+cfg.ServerPort, nil); err != nil {
-		log.Fatal("Failed to start server:", err)
+	if err := transport.Receive(ctx, botHandler.HandleMessage); err != nil {
+		log.Fatal("Transport stopped:", err)
 	}
 }
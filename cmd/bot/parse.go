@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/zenha/oliveiras/internal/parser"
+)
+
+// runParse parses a saved search results page and prints the extracted listings as JSON,
+// so parsers can be developed offline against fixture pages.
+// Usage: parse platform file start_date end_date
+func runParse(args []string) error {
+	if len(args) != 4 {
+		return fmt.Errorf("usage: parse platform file start_date end_date")
+	}
+
+	platform, path, startDate, endDate := args[0], args[1], args[2], args[3]
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var listings interface{}
+	switch platform {
+	case "airbnb":
+		listings, err = parser.ParseAirbnb(file, startDate, endDate)
+	case "booking":
+		listings, err = parser.ParseBooking(file, startDate, endDate)
+	case "vrbo":
+		listings, err = parser.ParseVrbo(file, startDate, endDate)
+	default:
+		return fmt.Errorf("no parser for platform %q", platform)
+	}
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(listings)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/zenha/oliveiras/internal/database"
+	"github.com/zenha/oliveiras/internal/scraper"
+)
+
+// runReprocess decodes every archived scrape again with the current code and rewrites the
+// stored listings, oldest scrape first so the newest data wins.
+// Usage: reprocess [platform] [since_date]
+func runReprocess(args []string, mongoClient *database.Client, archive *scraper.Archive) error {
+	if len(args) > 2 {
+		return fmt.Errorf("usage: reprocess [platform] [since_date]")
+	}
+
+	platform := ""
+	if len(args) > 0 && args[0] != "all" {
+		platform = args[0]
+	}
+	var since time.Time
+	if len(args) > 1 {
+		var err error
+		since, err = time.Parse("2006-01-02", args[1])
+		if err != nil {
+			return fmt.Errorf("invalid since date: %v", err)
+		}
+	}
+
+	records, err := mongoClient.GetArchivedScrapeRecords(platform, since)
+	if err != nil {
+		return fmt.Errorf("loading scrapes: %v", err)
+	}
+
+	// Reprocessing only reads the archive, so the service needs no providers
+	service := scraper.NewService(scraper.NewRegistry(), mongoClient, scraper.RetryPolicy{})
+	service.SetArchive(archive)
+
+	kept, rejected, failed := 0, 0, 0
+	for _, record := range records {
+		ok, bad, err := service.Reprocess(record)
+		if err != nil {
+			log.Printf("Failed to reprocess %s scrape %s: %v\n", record.Platform, record.ID.Hex(), err)
+			failed++
+			continue
+		}
+		kept += ok
+		rejected += bad
+	}
+
+	fmt.Printf("Reprocessed %d scrapes: %d listings for their stays, %d rejected, %d scrapes failed\n",
+		len(records)-failed, kept, rejected, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d scrapes failed", failed)
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/zenha/oliveiras/internal/scraper"
+)
+
+// anomalyAlertInterval is the least time between two alerts about the same platform and query,
+// so a broken parser does not flood the admins while a query is retried, yet a suspicious scrape
+// of another market or stay is still reported
+const anomalyAlertInterval = time.Hour
+
+// AlertAnomaly tells the admins about a suspicious scrape, at most once per platform and query
+// per interval
+func (h *Handler) AlertAnomaly(alert scraper.AnomalyAlert) {
+	log.Printf("Suspicious %s scrape in run %s: %s\n", alert.Platform, alert.RunID.Hex(), strings.Join(alert.Anomalies, "; "))
+
+	key := alert.Platform + "|" + alert.Query.Key()
+	h.alertMu.Lock()
+	if time.Since(h.lastAlert[key]) < anomalyAlertInterval {
+		h.alertMu.Unlock()
+		return
+	}
+	for other, sent := range h.lastAlert {
+		if time.Since(sent) >= anomalyAlertInterval {
+			delete(h.lastAlert, other)
+		}
+	}
+	h.lastAlert[key] = time.Now()
+	h.alertMu.Unlock()
+
+	message := fmt.Sprintf("Suspicious %s scrape for %s to %s, %s:\n- %s\nIts listings were not stored. Check the parser, then rebuild the data with reprocess. Run %s",
+		platformLabel(alert.Platform), alert.Query.StartDate, alert.Query.EndDate, alert.Query.Market.Describe(),
+		strings.Join(alert.Anomalies, "\n- "), alert.RunID.Hex())
+	for _, adminID := range h.cfg.AdminUserIDs {
+		if err := h.transport.SendMessage(adminID, message); err != nil {
+			log.Println("Failed to send anomaly alert:", err)
+		}
+	}
+}
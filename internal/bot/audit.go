@@ -0,0 +1,170 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zenha/oliveiras/internal/models"
+)
+
+// invocation tracks a single command run so its outcome can be audited
+type invocation struct {
+	models.Message
+	ctx     context.Context
+	command string
+	outcome string
+	err     error
+}
+
+// triggeredBy describes the invocation for the scrape runs it starts, e.g. "/scrape by ana (42)"
+func (inv *invocation) triggeredBy() string {
+	return fmt.Sprintf("%s by %s (%d)", inv.command, inv.UserName, inv.UserID)
+}
+
+// fail records the command failure and tells the user about it
+func (h *Handler) fail(inv *invocation, prefix string, err error) error {
+	inv.outcome = models.OutcomeError
+	inv.err = err
+	return h.transport.SendMessage(inv.ChatID, prefix+err.Error())
+}
+
+// usage records an invalid invocation and replies with the usage text
+func (h *Handler) usage(inv *invocation, text string) error {
+	inv.outcome = models.OutcomeUsage
+	return h.transport.SendMessage(inv.ChatID, text)
+}
+
+// logInvocation stores the invocation in the audit log. Failures are only logged so
+// that an audit problem never breaks the command itself.
+func (h *Handler) logInvocation(inv *invocation, parts []string, started time.Time, sendErr error) {
+	entry := &models.CommandLog{
+		UserID:     inv.UserID,
+		UserName:   inv.UserName,
+		ChatID:     inv.ChatID,
+		Command:    parts[0],
+		Args:       parts[1:],
+		StartedAt:  started.UTC(),
+		DurationMs: time.Since(started).Milliseconds(),
+		Outcome:    models.OutcomeOK,
+	}
+
+	err := inv.err
+	if sendErr != nil {
+		err = sendErr
+	}
+	if inv.outcome != "" {
+		entry.Outcome = inv.outcome
+	}
+	if err != nil {
+		entry.Outcome = models.OutcomeError
+		entry.Error = err.Error()
+	}
+
+	if err := h.mongoClient.InsertCommandLog(entry); err != nil {
+		log.Println("Failed to store command log:", err)
+	}
+}
+
+// usageStats aggregates audit log entries for one user or command
+type usageStats struct {
+	name     string
+	count    int
+	errors   int
+	duration time.Duration
+}
+
+func (s *usageStats) add(entry models.CommandLog) {
+	s.count++
+	if entry.Outcome == models.OutcomeError {
+		s.errors++
+	}
+	s.duration += time.Duration(entry.DurationMs) * time.Millisecond
+}
+
+func (s *usageStats) errorRate() float64 {
+	if s.count == 0 {
+		return 0
+	}
+	return float64(s.errors) / float64(s.count) * 100
+}
+
+// handleUsage summarizes command activity per user and command for admins
+func (h *Handler) handleUsage(inv *invocation, args []string) error {
+	if !inv.Local && !h.cfg.IsAdmin(inv.UserID) {
+		return h.usage(inv, "This command is only available to admins.")
+	}
+
+	days := 7
+	if len(args) > 0 {
+		parsed, err := strconv.Atoi(args[0])
+		if err != nil || parsed <= 0 {
+			return h.usage(inv, "Usage: /usage [days]")
+		}
+		days = parsed
+	}
+
+	entries, err := h.mongoClient.GetCommandLogsSince(time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		return h.fail(inv, "Error: ", err)
+	}
+	if len(entries) == 0 {
+		return h.transport.SendMessage(inv.ChatID, fmt.Sprintf("No commands in the last %d days.", days))
+	}
+
+	total := &usageStats{}
+	byUser := map[int]*usageStats{}
+	byCommand := map[string]*usageStats{}
+	for _, entry := range entries {
+		total.add(entry)
+
+		user, ok := byUser[entry.UserID]
+		if !ok {
+			user = &usageStats{name: fmt.Sprintf("%s (%d)", entry.UserName, entry.UserID)}
+			byUser[entry.UserID] = user
+		}
+		user.add(entry)
+
+		command, ok := byCommand[entry.Command]
+		if !ok {
+			command = &usageStats{name: entry.Command}
+			byCommand[entry.Command] = command
+		}
+		command.add(entry)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Usage in the last %d days: %d commands, %.1f%% errors\n", days, total.count, total.errorRate()))
+
+	sb.WriteString("\nPer user:\n")
+	for _, stats := range sortedUsage(byUser) {
+		sb.WriteString(fmt.Sprintf("%s: %d commands, %.1f%% errors\n", stats.name, stats.count, stats.errorRate()))
+	}
+
+	sb.WriteString("\nPer command:\n")
+	for _, stats := range sortedUsage(byCommand) {
+		average := stats.duration / time.Duration(stats.count)
+		sb.WriteString(fmt.Sprintf("%s: %d runs, %.1f%% errors, avg %s\n", stats.name, stats.count, stats.errorRate(), average.Round(time.Millisecond)))
+	}
+
+	return h.transport.SendMessage(inv.ChatID, sb.String())
+}
+
+// sortedUsage orders usage stats by descending count
+func sortedUsage[K comparable](stats map[K]*usageStats) []*usageStats {
+	result := make([]*usageStats, 0, len(stats))
+	for _, s := range stats {
+		result = append(result, s)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].count != result[j].count {
+			return result[i].count > result[j].count
+		}
+		return result[i].name < result[j].name
+	})
+	return result
+}
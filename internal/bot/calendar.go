@@ -0,0 +1,242 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zenha/oliveiras/internal/models"
+	"github.com/zenha/oliveiras/internal/scraper"
+)
+
+// maxCalendarNights caps the horizon a calendar scrape may cover
+const maxCalendarNights = 62
+
+// calendarUsage explains the /calendar command
+const calendarUsage = "Usage: /calendar [platform...] start_date end_date [nights=N] [field=value...] [--force]\n" +
+	"Scrapes a stay of N nights (default 1) starting on every night of the range and reports the price per night."
+
+// handleCalendar scrapes one stay per night of the horizon for every property in scope and
+// reports the per-night market price curve. The scrapes run in the background, as a long
+// horizon takes far longer than Telegram waits for the update to be answered.
+func (h *Handler) handleCalendar(inv *invocation, args []string) error {
+	args, force := extractFlag(args, "--force")
+	args, stayNights, err := extractStayNights(args)
+	if err != nil {
+		return h.usage(inv, err.Error()+"\n"+calendarUsage)
+	}
+	args, filters, err := extractMarketFilters(args)
+	if err != nil {
+		return h.usage(inv, err.Error()+"\n"+calendarUsage)
+	}
+	platforms, args := h.splitPlatforms(args)
+	if len(args) != 2 {
+		return h.usage(inv, calendarUsage)
+	}
+
+	nights, err := stayDates(args[0], args[1])
+	if err != nil {
+		return h.usage(inv, "Error: "+err.Error())
+	}
+	if len(nights) > maxCalendarNights {
+		return h.usage(inv, fmt.Sprintf("Range too long, use at most %d nights.", maxCalendarNights))
+	}
+
+	properties, err := h.scopedProperties(inv.ChatID)
+	if err != nil {
+		return h.fail(inv, "Error: ", err)
+	}
+
+	if err := h.transport.SendMessage(inv.ChatID, fmt.Sprintf("Scraping %d stays of %d nights in the background, %d at a time. Progress and the price curve will follow.", len(nights)*len(properties), stayNights, h.cfg.CalendarBatchSize)); err != nil {
+		return err
+	}
+
+	// The background run gets its own invocation so it does not race with the audit log
+	background := &invocation{Message: inv.Message, ctx: inv.ctx, command: inv.command}
+	go func() {
+		if err := h.runCalendar(background, properties, filters, nights, stayNights, platforms, force); err != nil {
+			log.Println("Calendar failed:", err)
+		}
+	}()
+	return nil
+}
+
+// runCalendar scrapes the calendar of every property and sends each property's price curve
+func (h *Handler) runCalendar(inv *invocation, properties []models.Property, filters []string, nights []string, stayNights int, platforms []string, force bool) error {
+	failed := 0
+	for _, property := range properties {
+		market := applyMarketFilters(property.Market, filters)
+		failed += h.scrapeCalendar(inv.ctx, inv.ChatID, property, market, nights, stayNights, platforms, force, inv.triggeredBy())
+		if inv.ctx.Err() != nil {
+			return inv.ctx.Err()
+		}
+
+		prices, err := h.mongoClient.GetNightPrices(nights[0], nights[len(nights)-1], stayNights, market)
+		if err != nil {
+			return h.fail(inv, propertyHeader(property)+"Error: ", err)
+		}
+		if err := h.transport.SendMessage(inv.ChatID, propertyHeader(property)+formatPriceCurve(nights, h.scheduler.Platforms(), prices)); err != nil {
+			return err
+		}
+	}
+
+	if failed > 0 {
+		return h.fail(inv, "", fmt.Errorf("%d calendar scrapes failed", failed))
+	}
+	return nil
+}
+
+// scrapeCalendar scrapes the stays of one property in batches through the queue, storing the
+// price of each night as its batch completes. It returns the number of failed platform scrapes.
+func (h *Handler) scrapeCalendar(ctx context.Context, chatID int, property models.Property, market models.Market, nights []string, stayNights int, platforms []string, force bool, triggeredBy string) int {
+	batchSize := max(h.cfg.CalendarBatchSize, 1)
+	failed := 0
+	for start := 0; start < len(nights); start += batchSize {
+		batch := nights[start:min(start+batchSize, len(nights))]
+
+		results := make([][]scraper.PlatformAnalysis, len(batch))
+		var wg sync.WaitGroup
+		for i, night := range batch {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				query := models.SearchQuery{StartDate: night, EndDate: addDays(night, stayNights), Market: market}
+				analyses, err := h.scheduler.Scrape(ctx, query, platforms, scraper.ScrapeOptions{Priority: scraper.PriorityNormal, Force: force, TriggeredBy: triggeredBy})
+				if err != nil {
+					log.Printf("Calendar scrape of %s failed: %v\n", night, err)
+					return
+				}
+				results[i] = analyses
+			}()
+		}
+		wg.Wait()
+		if ctx.Err() != nil {
+			return failed
+		}
+
+		prices := []models.NightPrice{}
+		for i, night := range batch {
+			if results[i] == nil {
+				failed++
+				continue
+			}
+			for _, result := range results[i] {
+				if result.Err != nil {
+					failed++
+					continue
+				}
+				prices = append(prices, nightPrice(result, night, stayNights, market))
+			}
+		}
+		if err := h.mongoClient.UpsertNightPrices(prices); err != nil {
+			log.Println("Failed to store night prices:", err)
+		}
+
+		progress := fmt.Sprintf("[%d/%d] %s%s to %s scraped", start+len(batch), len(nights), propertyPrefix(property), batch[0], batch[len(batch)-1])
+		if err := h.transport.SendMessage(chatID, progress); err != nil {
+			log.Println("Failed to send progress:", err)
+		}
+	}
+	return failed
+}
+
+// nightPrice summarizes the nightly prices of a stay's listings
+func nightPrice(result scraper.PlatformAnalysis, night string, stayNights int, market models.Market) models.NightPrice {
+	price := models.NightPrice{
+		Platform:   result.Platform,
+		Night:      night,
+		StayNights: stayNights,
+		Market:     market,
+		Listings:   len(result.Listings),
+		ScrapedAt:  result.ScrapedAt,
+	}
+	if len(result.Listings) == 0 {
+		return price
+	}
+
+	total := 0.0
+	for i, listing := range result.Listings {
+		nightly := listing.Price
+		total += nightly
+		if i == 0 || nightly < price.LowestPrice {
+			price.LowestPrice = nightly
+		}
+		if nightly > price.HighestPrice {
+			price.HighestPrice = nightly
+		}
+	}
+	price.AveragePrice = total / float64(len(result.Listings))
+	return price
+}
+
+// formatPriceCurve renders the average price of every night for the platforms with prices
+func formatPriceCurve(nights, allPlatforms []string, prices []models.NightPrice) string {
+	byNight := make(map[string]map[string]models.NightPrice)
+	stored := []string{}
+	for _, price := range prices {
+		if byNight[price.Night] == nil {
+			byNight[price.Night] = make(map[string]models.NightPrice)
+		}
+		byNight[price.Night][price.Platform] = price
+		stored = append(stored, price.Platform)
+	}
+	platforms := []string{}
+	for _, platform := range allPlatforms {
+		if slices.Contains(stored, platform) {
+			platforms = append(platforms, platform)
+		}
+	}
+	if len(platforms) == 0 {
+		return "No prices stored for these nights."
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Average price per night:\n")
+	for _, night := range nights {
+		day, _ := time.Parse("2006-01-02", night)
+		parts := []string{fmt.Sprintf("%s %s", night, day.Format("Mon"))}
+		for _, platform := range platforms {
+			price, ok := byNight[night][platform]
+			if !ok || price.Listings == 0 {
+				parts = append(parts, platformLabel(platform)+" -")
+				continue
+			}
+			parts = append(parts, fmt.Sprintf("%s %.0f", platformLabel(platform), price.AveragePrice))
+		}
+		sb.WriteString(strings.Join(parts, " | ") + "\n")
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// extractStayNights removes a nights=N argument, defaulting to single-night stays
+func extractStayNights(args []string) ([]string, int, error) {
+	remaining := []string{}
+	stayNights := 1
+	for _, arg := range args {
+		value, ok := strings.CutPrefix(arg, "nights=")
+		if !ok {
+			remaining = append(remaining, arg)
+			continue
+		}
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > 30 {
+			return nil, 0, fmt.Errorf("invalid nights value %q", value)
+		}
+		stayNights = parsed
+	}
+	return remaining, stayNights, nil
+}
+
+// addDays returns the date the given number of days after date
+func addDays(date string, days int) string {
+	day, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return date
+	}
+	return day.AddDate(0, 0, days).Format("2006-01-02")
+}
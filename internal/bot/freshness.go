@@ -0,0 +1,326 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/zenha/oliveiras/internal/models"
+	"github.com/zenha/oliveiras/internal/scraper"
+)
+
+// maxFreshnessNights caps the range a freshness report may cover
+const maxFreshnessNights = 62
+
+// platformFreshness summarizes one platform's stored listings for one stay date
+type platformFreshness struct {
+	count       int
+	lastScraped time.Time
+	stale       bool
+}
+
+// freshnessReport holds the freshness of every platform for a range of stay dates in one market
+type freshnessReport struct {
+	startDate string
+	endDate   string
+	dates     []string
+	// platforms are the registered platforms, in registry order
+	platforms []string
+	// optional are the platforms whose missing or stale data is not a gap
+	optional map[string]bool
+	// freshness maps each platform to the freshness of its stay dates
+	freshness map[string]map[string]platformFreshness
+}
+
+// stayDates returns every night from startDate up to, but excluding, endDate
+func stayDates(startDate, endDate string) ([]string, error) {
+	start, err := time.Parse("2006-01-02", startDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start date: %v", err)
+	}
+	end, err := time.Parse("2006-01-02", endDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid end date: %v", err)
+	}
+	if !end.After(start) {
+		return nil, fmt.Errorf("end date must be after start date")
+	}
+
+	dates := []string{}
+	for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+		dates = append(dates, day.Format("2006-01-02"))
+	}
+	return dates, nil
+}
+
+// nextDate returns the day after the given date
+func nextDate(date string) string {
+	day, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return date
+	}
+	return day.AddDate(0, 0, 1).Format("2006-01-02")
+}
+
+// propertyGaps holds the stay dates of a property's market that need scraping
+type propertyGaps struct {
+	property models.Property
+	dates    []string
+}
+
+// buildFreshnessReport collects, per stay date and platform, how much of the market's data is
+// stored and how old it is. Dates whose insertion time cannot be read count as stale.
+func (h *Handler) buildFreshnessReport(startDate, endDate string, market models.Market) (*freshnessReport, error) {
+	dates, err := stayDates(startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	if len(dates) > maxFreshnessNights {
+		return nil, fmt.Errorf("range too long, use at most %d nights", maxFreshnessNights)
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -h.cfg.FreshnessDays)
+	report := &freshnessReport{
+		startDate: startDate,
+		endDate:   endDate,
+		dates:     dates,
+		platforms: h.scheduler.Platforms(),
+		optional:  make(map[string]bool),
+		freshness: make(map[string]map[string]platformFreshness),
+	}
+
+	for _, platform := range report.platforms {
+		listings, err := h.mongoClient.GetListings(platform, market, startDate, endDate, time.Time{})
+		if err != nil {
+			return nil, err
+		}
+
+		report.optional[platform] = h.cfg.IsOptionalPlatform(platform)
+		report.freshness[platform] = make(map[string]platformFreshness)
+		for date, dateListings := range separateByDate(listings) {
+			latest, err := latestInsert(dateListings)
+			if err != nil {
+				log.Printf("Error parsing %s inserted_at: %v", platformLabel(platform), err)
+			}
+			report.freshness[platform][date] = platformFreshness{
+				count:       len(dateListings),
+				lastScraped: latest,
+				stale:       err != nil || !latest.After(cutoff),
+			}
+		}
+	}
+
+	return report, nil
+}
+
+// findGaps returns, for every property with any, the missing or stale dates of its market
+func (h *Handler) findGaps(startDate, endDate string, properties []models.Property) ([]propertyGaps, error) {
+	found := []propertyGaps{}
+	for _, property := range properties {
+		report, err := h.buildFreshnessReport(startDate, endDate, property.Market)
+		if err != nil {
+			return nil, err
+		}
+		if gaps := report.gaps(); len(gaps) > 0 {
+			found = append(found, propertyGaps{property: property, dates: gaps})
+		}
+	}
+	return found, nil
+}
+
+// countGaps returns the number of single-night scrapes needed to fill the gaps
+func countGaps(gaps []propertyGaps) int {
+	total := 0
+	for _, property := range gaps {
+		total += len(property.dates)
+	}
+	return total
+}
+
+// gaps returns the stay dates where any required platform is missing or stale. Optional
+// platforms are left out, as rescraping cannot fill a market they do not cover.
+func (r *freshnessReport) gaps() []string {
+	gaps := []string{}
+	for _, date := range r.dates {
+		for _, platform := range r.platforms {
+			if r.optional[platform] {
+				continue
+			}
+			if freshness, ok := r.freshness[platform][date]; !ok || freshness.stale {
+				gaps = append(gaps, date)
+				break
+			}
+		}
+	}
+	return gaps
+}
+
+// format renders the report as one line per stay date
+func (r *freshnessReport) format(freshnessDays int) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Freshness %s to %s (stale after %d days):\n", r.startDate, r.endDate, freshnessDays))
+
+	missing, stale := 0, 0
+	for _, date := range r.dates {
+		sb.WriteString(date)
+		for _, platform := range r.platforms {
+			sb.WriteString(fmt.Sprintf(" | %s: %s", platformLabel(platform), formatPlatformFreshness(r.freshness[platform], date)))
+			if r.optional[platform] {
+				continue
+			}
+			freshness, ok := r.freshness[platform][date]
+			if !ok {
+				missing++
+			} else if freshness.stale {
+				stale++
+			}
+		}
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString(fmt.Sprintf("\nMissing: %d. Stale: %d.", missing, stale))
+	return sb.String()
+}
+
+func formatPlatformFreshness(platform map[string]platformFreshness, date string) string {
+	freshness, ok := platform[date]
+	if !ok {
+		return "MISSING"
+	}
+	age := "unknown age"
+	if !freshness.lastScraped.IsZero() {
+		age = formatAge(time.Since(freshness.lastScraped)) + " ago"
+	}
+	status := fmt.Sprintf("%d listings, %s", freshness.count, age)
+	if freshness.stale {
+		status += " STALE"
+	}
+	return status
+}
+
+// formatAge renders a duration in the largest whole unit
+func formatAge(age time.Duration) string {
+	switch {
+	case age < time.Hour:
+		return fmt.Sprintf("%dm", int(age.Minutes()))
+	case age < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(age.Hours()))
+	default:
+		return fmt.Sprintf("%dd", int(age.Hours()/24))
+	}
+}
+
+// handleFreshness reports when each stay date was last scraped in every property's market and
+// offers to scrape the gaps
+func (h *Handler) handleFreshness(inv *invocation, args []string) error {
+	if len(args) != 2 {
+		return h.usage(inv, "Usage: /freshness start_date end_date")
+	}
+
+	properties, err := h.scopedProperties(inv.ChatID)
+	if err != nil {
+		return h.fail(inv, "Error: ", err)
+	}
+
+	for _, property := range properties {
+		report, err := h.buildFreshnessReport(args[0], args[1], property.Market)
+		if err != nil {
+			return h.fail(inv, "Error: ", err)
+		}
+
+		message := propertyHeader(property) + report.format(h.cfg.FreshnessDays)
+		gaps := report.gaps()
+		if len(gaps) == 0 {
+			if err := h.transport.SendMessage(inv.ChatID, message); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := h.transport.SendMessageWithButtons(inv.ChatID, message, []models.InlineKeyboardButton{
+			{
+				Text:         fmt.Sprintf("Scrape %d gaps", len(gaps)),
+				CallbackData: fmt.Sprintf("/scrapegaps %s %s", args[0], args[1]),
+			},
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// handleScrapeGaps scrapes single-night stays for every missing or stale date in the range of
+// each property's market. The scrapes run in the background, reporting progress as they go.
+func (h *Handler) handleScrapeGaps(inv *invocation, args []string) error {
+	if len(args) != 2 {
+		return h.usage(inv, "Usage: /scrapegaps start_date end_date")
+	}
+
+	properties, err := h.scopedProperties(inv.ChatID)
+	if err != nil {
+		return h.fail(inv, "Error: ", err)
+	}
+
+	gaps, err := h.findGaps(args[0], args[1], properties)
+	if err != nil {
+		return h.fail(inv, "Error: ", err)
+	}
+	if len(gaps) == 0 {
+		return h.transport.SendMessage(inv.ChatID, "All dates are up to date.")
+	}
+
+	for _, property := range gaps {
+		if err := h.transport.SendMessage(inv.ChatID, fmt.Sprintf("Scraping %d dates in the background: %s%s", len(property.dates), propertyPrefix(property.property), strings.Join(property.dates, ", "))); err != nil {
+			return err
+		}
+	}
+
+	// The background run gets its own invocation so it does not race with the audit log
+	background := &invocation{Message: inv.Message, ctx: inv.ctx, command: inv.command}
+	go func() {
+		var err error
+		if failed := h.scrapeDates(background.ctx, background.ChatID, gaps, background.triggeredBy()); failed > 0 {
+			err = h.fail(background, "", fmt.Errorf("%d scrapes failed", failed))
+		} else {
+			err = h.transport.SendMessage(background.ChatID, "All gaps scraped.")
+		}
+		if err != nil {
+			log.Println("Failed to send message:", err)
+		}
+	}()
+	return nil
+}
+
+// scrapeDates scrapes a single-night stay for each gap date of each property, reporting
+// progress as each one completes. It returns the number of scrapes where at least one
+// platform failed.
+func (h *Handler) scrapeDates(ctx context.Context, chatID int, gaps []propertyGaps, triggeredBy string) int {
+	total := countGaps(gaps)
+	done, failed := 0, 0
+	for _, gap := range gaps {
+		property := gap.property
+		for _, date := range gap.dates {
+			done++
+			progress := fmt.Sprintf("[%d/%d] %s%s: ", done, total, propertyPrefix(property), date)
+
+			query := models.SearchQuery{StartDate: date, EndDate: nextDate(date), Market: property.Market}
+			results, err := h.scheduler.Scrape(ctx, query, nil, scraper.ScrapeOptions{Priority: scraper.PriorityNormal, TriggeredBy: triggeredBy})
+			if err != nil {
+				failed++
+				progress += scrapeErrorMessage(err)
+			} else {
+				if len(scraper.Failed(results)) > 0 {
+					failed++
+				}
+				progress += formatAnalysisSummary(results)
+			}
+
+			if err := h.transport.SendMessage(chatID, progress); err != nil {
+				log.Println("Failed to send progress:", err)
+			}
+		}
+	}
+	return failed
+}
@@ -1,133 +1,266 @@
 package bot
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/zenha/oliveiras/internal/database"
 	"github.com/zenha/oliveiras/internal/gemini"
+	"github.com/zenha/oliveiras/internal/models"
 	"github.com/zenha/oliveiras/internal/scraper"
-	"github.com/zenha/oliveiras/internal/telegram"
 	"github.com/zenha/oliveiras/pkg/config"
 )
 
 // Handler manages bot message handling
 type Handler struct {
-	telegramClient *telegram.Client
-	scraperService *scraper.Service
+	transport   Transport
+	scheduler   *scraper.Scheduler
+	mongoClient *database.Client
+	cfg         *config.Config
+
+	alertMu   sync.Mutex
+	lastAlert map[string]time.Time
 }
 
-// NewHandler creates a new bot handler
-func NewHandler(telegramClient *telegram.Client, scraperService *scraper.Service) *Handler {
+// NewHandler creates a new bot handler replying through the given transport
+func NewHandler(transport Transport, scheduler *scraper.Scheduler, mongoClient *database.Client, cfg *config.Config) *Handler {
 	return &Handler{
-		telegramClient: telegramClient,
-		scraperService: scraperService,
+		transport:   transport,
+		scheduler:   scheduler,
+		mongoClient: mongoClient,
+		cfg:         cfg,
+		lastAlert:   make(map[string]time.Time),
 	}
 }
 
-// HandleMessage processes incoming bot messages
-func (h *Handler) HandleMessage(chatID int, message string) error {
-	// Load configuration
-	cfg, err := config.Load()
-	if err != nil {
-		log.Fatal("Failed to load configuration:", err)
+// HandleMessage processes incoming bot messages and records them in the audit log
+func (h *Handler) HandleMessage(ctx context.Context, msg models.Message) error {
+	parts := strings.Fields(msg.Text)
+	if len(parts) == 0 {
+		return nil
 	}
 
-	// Initialize MongoDB client
-	mongoClient, err := database.NewClient(cfg.MongoURI)
-	if err != nil {
-		log.Fatal("Failed to connect to MongoDB:", err)
+	inv := &invocation{Message: msg, ctx: ctx, command: parts[0]}
+	started := time.Now()
+	err := h.dispatch(inv, parts[0], parts[1:])
+	h.logInvocation(inv, parts, started, err)
+	return err
+}
+
+// dispatch routes a command to its handler
+func (h *Handler) dispatch(inv *invocation, command string, args []string) error {
+	switch command {
+	case "/scrape":
+		return h.handleScrape(inv, args)
+
+	case "/getprices":
+		return h.handleGetPrices(inv, args)
+
+	case "/freshness":
+		return h.handleFreshness(inv, args)
+
+	case "/scrapegaps":
+		return h.handleScrapeGaps(inv, args)
+
+	case "/calendar":
+		return h.handleCalendar(inv, args)
+
+	case "/properties":
+		return h.handleProperties(inv)
+
+	case "/property":
+		return h.handleProperty(inv, args)
+
+	case "/usage":
+		return h.handleUsage(inv, args)
+
+	case "/selftest":
+		return h.handleSelfTest(inv, args)
+
+	case "/runs":
+		return h.handleRuns(inv, args)
+
+	default:
+		return h.usage(inv, "Unknown command: "+command+".\nUse /scrape command to scrape and analyze listings.\nUse /getprices command to get the prices suggestions.\nUse /freshness command to check how recent the stored data is.\nUse /calendar command to get the market price of every night.\nUse /properties and /property to manage your properties.\nUse /selftest to check the scrapers.\nUse /runs to see the latest scrape runs.")
 	}
-	defer mongoClient.Disconnect()
+}
 
-	parts := strings.Split(message, " ")
-	if len(parts) == 0 {
-		return nil
+// handleScrape scrapes and analyzes listings for every property in scope
+func (h *Handler) handleScrape(inv *invocation, args []string) error {
+	usage := "Usage: /scrape [platform...] start_date end_date [field=value...] [--force]\nPlatforms: " + strings.Join(h.scheduler.Platforms(), ", ") + "\nFilters: " + marketFields
+	args, force := extractFlag(args, "--force")
+	args, filters, err := extractMarketFilters(args)
+	if err != nil {
+		return h.usage(inv, err.Error()+"\n"+usage)
+	}
+	platforms, args := h.splitPlatforms(args)
+	if len(args) != 2 {
+		return h.usage(inv, usage)
 	}
 
-	switch parts[0] {
-	case "/scrape":
-		if len(parts) != 3 {
-			return h.telegramClient.SendMessage(chatID, "Usage: /scrape start_date end_date")
-		}
+	startDate := args[0]
+	endDate := args[1]
 
-		startDate := parts[1]
-		endDate := parts[2]
+	properties, err := h.scopedProperties(inv.ChatID)
+	if err != nil {
+		return h.fail(inv, "Error: ", err)
+	}
 
-		airbnbAnalysis, bookingAnalysis, err := h.scraperService.ScrapeListings(startDate, endDate)
+	for _, property := range properties {
+		query := models.SearchQuery{StartDate: startDate, EndDate: endDate, Market: applyMarketFilters(property.Market, filters)}
+		results, err := h.scheduler.Scrape(inv.ctx, query, platforms, scraper.ScrapeOptions{Priority: scraper.PriorityHigh, Force: force, TriggeredBy: inv.triggeredBy()})
 		if err != nil {
-			return h.telegramClient.SendMessage(chatID, "Error: "+err.Error())
+			inv.err = err
+			if err := h.transport.SendMessage(inv.ChatID, propertyHeader(property)+scrapeErrorMessage(err)); err != nil {
+				return err
+			}
+			continue
+		}
+		if failed := scraper.Failed(results); len(failed) > 0 {
+			inv.err = failed[0].Err
 		}
 
-		response := formatAnalysisResponse(airbnbAnalysis, bookingAnalysis)
-		return h.telegramClient.SendMessage(chatID, response)
-
-	case "/getprices":
-		if len(parts) != 3 {
-			return h.telegramClient.SendMessage(chatID, "Usage: /getprices start_date end_date")
+		response := propertyHeader(property) + formatAnalysisResponse(results)
+		if err := h.transport.SendMessage(inv.ChatID, response); err != nil {
+			return err
 		}
+	}
+	return nil
+}
+
+// handleGetPrices asks Gemini for price suggestions for every property in scope.
+// With --auto, missing or stale dates are scraped in the background first.
+func (h *Handler) handleGetPrices(inv *invocation, args []string) error {
+	args, auto := extractFlag(args, "--auto")
+	if len(args) != 2 {
+		return h.usage(inv, "Usage: /getprices start_date end_date [--auto]")
+	}
+
+	startDate := args[0]
+	endDate := args[1]
+
+	properties, err := h.scopedProperties(inv.ChatID)
+	if err != nil {
+		return h.fail(inv, "Error: ", err)
+	}
 
-		startDate := parts[1]
-		endDate := parts[2]
-
-		// airbnbListings, err := mongoClient.GetAirbnbByDate(startDate, endDate)
-		// if err != nil {
-		// 	return h.telegramClient.SendMessage(chatID, "Error: "+err.Error())
-		// }
-		// bookingListings, err := mongoClient.GetBookingByDate(startDate, endDate)
-		// if err != nil {
-		// 	return h.telegramClient.SendMessage(chatID, "Error: "+err.Error())
-		// }
-
-		// airbnbDateList, err := separateAirbnbByDate(airbnbListings)
-		// if err != nil {
-		// 	return h.telegramClient.SendMessage(chatID, "Error: "+err.Error())
-		// }
-		// bookingDateList, err := separateBookingByDate(bookingListings)
-		// if err != nil {
-		// 	return h.telegramClient.SendMessage(chatID, "Error: "+err.Error())
-		// }
-
-		// airbnbOutOfDateList := getAirbnbOutOfDateList(airbnbDateList)
-		// bookingOutOfDateList := getBookingOutOfDateList(bookingDateList)
-		// if airbnbOutOfDateList != "" || bookingOutOfDateList != "" {
-		// 	return h.telegramClient.SendMessage(chatID, "Data is not up to date. Please run /scrape command. Airbnbs: "+airbnbOutOfDateList+". Bookings: "+bookingOutOfDateList+".")
-		// }
-
-		airbnbListings, err := mongoClient.GetAirbnbUpToDate(startDate, endDate)
+	if auto {
+		gaps, err := h.findGaps(startDate, endDate, properties)
 		if err != nil {
-			return h.telegramClient.SendMessage(chatID, "Failed to getAirbnbUpToDate. Error: "+err.Error())
+			return h.fail(inv, "Error: ", err)
 		}
-		if len(airbnbListings) == 0 {
-			return h.telegramClient.SendMessage(chatID, "No Airbnb results that are up to date. Scrape the content for those dates using /scrape command.")
+
+		if len(gaps) > 0 {
+			if err := h.transport.SendMessage(inv.ChatID, fmt.Sprintf("Scraping %d missing or stale dates in the background. Price suggestions will follow.", countGaps(gaps))); err != nil {
+				return err
+			}
+			// The background run gets its own invocation so it does not race with the audit log
+			background := &invocation{Message: inv.Message, ctx: inv.ctx, command: inv.command}
+			go func() {
+				failed := h.scrapeDates(background.ctx, background.ChatID, gaps, background.triggeredBy())
+				if failed > 0 {
+					if err := h.transport.SendMessage(background.ChatID, fmt.Sprintf("%d scrapes failed. Suggestions use the data available.", failed)); err != nil {
+						log.Println("Failed to send message:", err)
+					}
+				}
+				if err := h.suggestPrices(background, startDate, endDate, properties); err != nil {
+					log.Println("Failed to suggest prices:", err)
+				}
+			}()
+			return nil
 		}
+	}
 
-		bookingListings, err := mongoClient.GetBookingUpToDate(startDate, endDate)
-		if err != nil {
-			return h.telegramClient.SendMessage(chatID, "Failed to getBookingUpToDate. Error: "+err.Error())
+	return h.suggestPrices(inv, startDate, endDate, properties)
+}
+
+// suggestPrices sends Gemini price suggestions built from up-to-date listings
+func (h *Handler) suggestPrices(inv *invocation, startDate, endDate string, properties []models.Property) error {
+	cutoff := time.Now().AddDate(0, 0, -h.cfg.FreshnessDays)
+
+	platforms := h.scheduler.Platforms()
+
+	geminiClient, err := gemini.NewClient(h.cfg.GeminiKey)
+	if err != nil {
+		return h.fail(inv, "Failed to create Gemini client:", err)
+	}
+
+	for _, property := range properties {
+		header := propertyHeader(property)
+
+		// Optional platforms without data are left out; a required one without data leaves
+		// the property without suggestions
+		comparables := make(map[string][]models.StoredListing, len(platforms))
+		missing := ""
+		for _, platform := range platforms {
+			// Only listings of the property's own market are compared against it
+			listings, err := h.mongoClient.GetListings(platform, property.Market, startDate, endDate, cutoff)
+			if err != nil {
+				return h.fail(inv, header+fmt.Sprintf("Failed to get %s listings. Error: ", platformLabel(platform)), err)
+			}
+			listings = filterComparables(listings, property)
+			if len(listings) == 0 && !h.cfg.IsOptionalPlatform(platform) {
+				missing = platform
+				break
+			}
+			comparables[platform] = listings
 		}
-		if len(bookingListings) == 0 {
-			return h.telegramClient.SendMessage(chatID, "No Booking results that are up to date. Scrape the content for those dates using /scrape command.")
+		if missing != "" {
+			if err := h.transport.SendMessage(inv.ChatID, header+fmt.Sprintf("No %s results that are up to date. Scrape the content for those dates using /scrape command or run /getprices with --auto.", platformLabel(missing))); err != nil {
+				return err
+			}
+			continue
 		}
 
-		geminiClient, err := gemini.NewClient(cfg.GeminiKey)
-		if err != nil {
-			return h.telegramClient.SendMessage(chatID, "Failed to create Gemini client:"+err.Error())
+		houseInfo := property.Profile.Describe()
+		sections := []string{}
+		for _, platform := range platforms {
+			if len(comparables[platform]) == 0 {
+				continue
+			}
+			prices, err := gemini.GenerateContent(geminiClient, houseInfo, gemini.PreparePrompt(comparables[platform]))
+			if err != nil {
+				return h.fail(inv, header+"Error: ", err)
+			}
+			sections = append(sections, fmt.Sprintf("%s Prices:\n%v", platformLabel(platform), prices))
 		}
-		bookingPrices, err := gemini.GenerateContent(geminiClient, gemini.PrepareBookingPrompt(bookingListings))
-		if err != nil {
-			return h.telegramClient.SendMessage(chatID, "Error: "+err.Error())
+		if err := h.transport.SendMessage(inv.ChatID, header+strings.Join(sections, "\n\n")); err != nil {
+			return err
 		}
-		airbnbPrices, err := gemini.GenerateContent(geminiClient, gemini.PrepareAirbnbPrompt(airbnbListings))
-		if err != nil {
-			return h.telegramClient.SendMessage(chatID, "Error: "+err.Error())
+	}
+	return nil
+}
+
+// extractFlag removes a flag from the arguments and reports whether it was present
+func extractFlag(args []string, flag string) ([]string, bool) {
+	remaining := []string{}
+	found := false
+	for _, arg := range args {
+		if arg == flag {
+			found = true
+			continue
 		}
+		remaining = append(remaining, arg)
+	}
+	return remaining, found
+}
 
-		telegramMessage := fmt.Sprintf("Booking Prices:\n%v\n\nAirbnb Prices:\n%v", bookingPrices, airbnbPrices)
-		return h.telegramClient.SendMessage(chatID, telegramMessage)
+// propertyHeader returns the line that introduces a property's results
+func propertyHeader(property models.Property) string {
+	if property.Name == defaultProperty.Name {
+		return ""
+	}
+	return "Property: " + property.Name + "\n\n"
+}
 
-	default:
-		return h.telegramClient.SendMessage(chatID, "Unknown command: "+parts[0]+".\nUse /scrape command to scrape and analyze listings.\nUse /getprices command to get the prices suggestions.")
+// propertyPrefix returns a short property label for progress lines
+func propertyPrefix(property models.Property) string {
+	if property.Name == defaultProperty.Name {
+		return ""
 	}
+	return property.Name + " "
 }
@@ -1,149 +1,178 @@
 package bot
 
 import (
+	"errors"
 	"fmt"
-	"log"
 	"strings"
 	"time"
 
 	"github.com/zenha/oliveiras/internal/models"
+	"github.com/zenha/oliveiras/internal/scraper"
 )
 
 // formatAnalysisResponse formats the analysis results into a readable message
-func formatAnalysisResponse(airbnb, booking *models.ListingAnalysis) string {
-	return fmt.Sprintf("Airbnb Listings Data:\nAverage Price: %.2f\nHighest Price: %.2f\nLowest Price: %.2f\nTotal Listings: %d\n\nBooking Listings Data:\nAverage Price: %.2f\nHighest Price: %.2f\nLowest Price: %.2f\nTotal Listings: %d",
-		airbnb.AveragePrice, airbnb.HighestPrice, airbnb.LowestPrice, airbnb.TotalListings,
-		booking.AveragePrice, booking.HighestPrice, booking.LowestPrice, booking.TotalListings)
-}
-
-func checkAirbnbDataUpToDate(airbnbListings []models.AirbnbData) (bool, error) {
-	for _, listing := range airbnbListings {
-		if !strings.Contains(listing.InsertedAt, "Z") {
-			listing.InsertedAt += "Z"
+func formatAnalysisResponse(results []scraper.PlatformAnalysis) string {
+	sections := make([]string, 0, len(results))
+	for _, result := range results {
+		if result.Err != nil {
+			sections = append(sections, platformFailure(result))
+			continue
 		}
-		insertedAt, err := time.Parse(time.RFC3339, listing.InsertedAt)
-		if err != nil {
-			log.Println("Error parsing Airbnb inserted_at:", err)
-			return false, err
+		section := fmt.Sprintf("%s Listings Data:\nAverage Price: %.2f\nHighest Price: %.2f\nLowest Price: %.2f\nTotal Listings: %d",
+			platformLabel(result.Platform), result.Analysis.AveragePrice, result.Analysis.HighestPrice, result.Analysis.LowestPrice, result.Analysis.TotalListings)
+		if result.Rejected > 0 {
+			section += fmt.Sprintf("\nRejected Listings: %d", result.Rejected)
 		}
-
-		if insertedAt.After(time.Now().AddDate(0, 0, -7)) {
-			return true, nil
+		if result.Skipped > 0 {
+			section += fmt.Sprintf("\nMalformed Records Skipped: %d", result.Skipped)
+		}
+		if result.Cached {
+			section += "\n" + cachedNote(result)
+		}
+		if result.Diff != nil {
+			section += "\n" + formatListingDiff(result.Diff)
 		}
+		sections = append(sections, section)
 	}
-	return false, nil
+	return strings.Join(sections, "\n\n")
 }
 
-func checkBookingDataUpToDate(bookingListings []models.BookingData) (bool, error) {
-	for _, listing := range bookingListings {
-		if !strings.Contains(listing.InsertedAt, "Z") {
-			listing.InsertedAt += "Z"
-		}
-		insertedAt, err := time.Parse(time.RFC3339, listing.InsertedAt)
-		if err != nil {
-			log.Println("Error parsing Booking inserted_at:", err)
-			return false, err
-		}
+// formatAnalysisSummary formats the analysis results into a single line
+func formatAnalysisSummary(results []scraper.PlatformAnalysis) string {
+	parts := make([]string, 0, len(results))
+	for _, result := range results {
+		if result.Err != nil {
+			parts = append(parts, platformLabel(result.Platform)+" failed")
+			continue
+		}
+		part := fmt.Sprintf("%s %d (avg %.2f", platformLabel(result.Platform), result.Analysis.TotalListings, result.Analysis.AveragePrice)
+		if result.Diff != nil && result.Diff.Changed() {
+			part += fmt.Sprintf(", +%d/−%d", len(result.Diff.New), len(result.Diff.Gone))
+		}
+		parts = append(parts, part+")")
+	}
+	return strings.Join(parts, ", ")
+}
 
-		if insertedAt.After(time.Now().AddDate(0, 0, -7)) {
-			return true, nil
-		}
+// cachedNote tells the user a result comes from an earlier scrape
+func cachedNote(result scraper.PlatformAnalysis) string {
+	minutes := int(time.Since(result.ScrapedAt).Minutes())
+	if minutes == 1 {
+		return "Scraped 1 minute ago, use --force to scrape again."
 	}
-	return false, nil
+	return fmt.Sprintf("Scraped %d minutes ago, use --force to scrape again.", minutes)
 }
 
-func filterBookingDataUpToDate(bookingListings []models.BookingData) ([]models.BookingData, error) {
-	upToDateList := []models.BookingData{}
-	for _, listing := range bookingListings {
-		if !strings.Contains(listing.InsertedAt, "Z") {
-			listing.InsertedAt += "Z"
-		}
-		insertedAt, err := time.Parse(time.RFC3339, listing.InsertedAt)
-		if err != nil {
-			log.Println("Error parsing Booking inserted_at:", err)
-			return []models.BookingData{}, err
-		}
+// formatListingDiff summarizes the changes since the previous scrape, e.g.
+// "+4 new, −7 gone (likely booked), 12 price drops avg −8%"
+func formatListingDiff(diff *scraper.ListingDiff) string {
+	since := fmt.Sprintf("the scrape %s ago", formatAge(time.Since(diff.Since)))
+	if !diff.Changed() {
+		return fmt.Sprintf("No changes since %s.", since)
+	}
 
-		if insertedAt.After(time.Now().AddDate(0, 0, -7)) {
-			upToDateList = append(upToDateList, bookingListings...)
-		}
+	parts := []string{
+		fmt.Sprintf("+%d new", len(diff.New)),
+		fmt.Sprintf("−%d gone (likely booked)", len(diff.Gone)),
 	}
-	return upToDateList, nil
+	if len(diff.PriceDrops) > 0 {
+		parts = append(parts, fmt.Sprintf("%d price drops avg %s", len(diff.PriceDrops), signedPercent(scraper.AveragePercent(diff.PriceDrops))))
+	}
+	if len(diff.PriceRises) > 0 {
+		parts = append(parts, fmt.Sprintf("%d price rises avg %s", len(diff.PriceRises), signedPercent(scraper.AveragePercent(diff.PriceRises))))
+	}
+	return fmt.Sprintf("Since %s: %s", since, strings.Join(parts, ", "))
 }
 
-func filterAirbnbDataUpToDate(airbnbListings []models.AirbnbData) ([]models.AirbnbData, error) {
-	upToDateList := []models.AirbnbData{}
-	for _, listing := range airbnbListings {
-		if !strings.Contains(listing.InsertedAt, "Z") {
-			listing.InsertedAt += "Z"
-		}
-		insertedAt, err := time.Parse(time.RFC3339, listing.InsertedAt)
-		if err != nil {
-			log.Println("Error parsing Airbnb inserted_at:", err)
-			return []models.AirbnbData{}, err
-		}
-
-		if insertedAt.After(time.Now().AddDate(0, 0, -7)) {
-			upToDateList = append(upToDateList, airbnbListings...)
-		}
+// signedPercent renders a relative change with its sign, e.g. "+5%" or "−8%"
+func signedPercent(percent float64) string {
+	if percent < 0 {
+		return fmt.Sprintf("−%.0f%%", -percent)
 	}
-	return upToDateList, nil
+	return fmt.Sprintf("+%.0f%%", percent)
 }
 
-func separateAirbnbByDate(listings []models.AirbnbData) (map[string][]models.AirbnbData, error) {
-	result := make(map[string][]models.AirbnbData)
-	for _, listing := range listings {
-		result[listing.StartDate] = append(result[listing.StartDate], listing)
+// platformFailure explains why a platform failed, e.g. "Booking failed: blocked"
+func platformFailure(result scraper.PlatformAnalysis) string {
+	reason := result.Err.Error()
+	var anomaly *scraper.AnomalyError
+	if errors.Is(result.Err, scraper.ErrTimeout) {
+		reason = "the scrape took too long and was stopped"
+	} else if errors.As(result.Err, &anomaly) {
+		reason = fmt.Sprintf("the results look wrong (%s) and were not stored", strings.Join(anomaly.Anomalies, "; "))
 	}
-	if len(result) == 0 {
-		return nil, fmt.Errorf("no listings found")
+	message := fmt.Sprintf("%s failed: %s", platformLabel(result.Platform), reason)
+	if result.Attempts > 1 {
+		message += fmt.Sprintf(" (after %d attempts)", result.Attempts)
 	}
-	return result, nil
+	return message
 }
 
-func separateBookingByDate(listings []models.BookingData) (map[string][]models.BookingData, error) {
-	result := make(map[string][]models.BookingData)
-	for _, listing := range listings {
-		result[listing.StartDate] = append(result[listing.StartDate], listing)
+// platformLabel returns the display name of a platform
+func platformLabel(platform string) string {
+	if platform == "" {
+		return platform
 	}
-	if len(result) == 0 {
-		return nil, fmt.Errorf("no listings found")
+	return strings.ToUpper(platform[:1]) + platform[1:]
+}
+
+// splitPlatforms separates leading platform names from the remaining arguments
+func (h *Handler) splitPlatforms(args []string) ([]string, []string) {
+	platforms := []string{}
+	for len(args) > 0 && h.scheduler.IsPlatform(args[0]) {
+		platforms = append(platforms, args[0])
+		args = args[1:]
+	}
+	return platforms, args
+}
+
+// parseInsertedAt parses the scraper's inserted_at timestamp, which may lack a zone suffix
+func parseInsertedAt(insertedAt string) (time.Time, error) {
+	if !strings.Contains(insertedAt, "Z") {
+		insertedAt += "Z"
 	}
-	return result, nil
+	return time.Parse(time.RFC3339, insertedAt)
 }
 
-func getAirbnbOutOfDateList(lists map[string][]models.AirbnbData) string {
-	outdatedDates := []string{}
-	for date, listings := range lists {
-		isUpToDate, err := checkAirbnbDataUpToDate(listings)
+// latestInsert returns the most recent inserted_at among the listings
+func latestInsert(listings []models.StoredListing) (time.Time, error) {
+	var latest time.Time
+	for _, listing := range listings {
+		insertedAt, err := parseInsertedAt(listing.InsertedAt)
 		if err != nil {
-			log.Println("Error checking Airbnb data:", err)
-			return ""
+			return time.Time{}, err
 		}
-		if !isUpToDate {
-			outdatedDates = append(outdatedDates, date)
+		if insertedAt.After(latest) {
+			latest = insertedAt
 		}
 	}
-	if len(outdatedDates) > 0 {
-		return strings.Join(outdatedDates, ",")
+	return latest, nil
+}
+
+// separateByDate groups listings by their stay's start date
+func separateByDate(listings []models.StoredListing) map[string][]models.StoredListing {
+	result := make(map[string][]models.StoredListing)
+	for _, listing := range listings {
+		result[listing.StartDate] = append(result[listing.StartDate], listing)
 	}
-	return ""
+	return result
 }
 
-func getBookingOutOfDateList(lists map[string][]models.BookingData) string {
-	outdatedDates := []string{}
-	for date, listings := range lists {
-		isUpToDate, err := checkBookingDataUpToDate(listings)
-		if err != nil {
-			log.Println("Error checking Booking data:", err)
-			return ""
-		}
-		if !isUpToDate {
-			outdatedDates = append(outdatedDates, date)
+// filterComparables keeps the listings the property is compared against
+func filterComparables(listings []models.StoredListing, property models.Property) []models.StoredListing {
+	result := []models.StoredListing{}
+	for _, listing := range listings {
+		if property.IsComparable(listing.URL) {
+			result = append(result, listing)
 		}
 	}
-	if len(outdatedDates) > 0 {
-		return strings.Join(outdatedDates, ",")
+	return result
+}
+
+// scrapeErrorMessage explains a scraper failure to the user
+func scrapeErrorMessage(err error) string {
+	if errors.Is(err, scraper.ErrTimeout) {
+		return "The scrape took too long and was stopped. The platforms may be slow or blocking us, try a shorter date range or try again later."
 	}
-	return ""
+	return "Error: " + err.Error()
 }
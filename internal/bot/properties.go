@@ -0,0 +1,329 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/zenha/oliveiras/internal/models"
+)
+
+// defaultProperty describes the original house and is used until properties are configured
+var defaultProperty = models.Property{
+	Name: "default",
+	Profile: models.PropertyProfile{
+		Bedrooms:       3,
+		DoubleBedrooms: 2,
+		SingleBedrooms: 1,
+		Bathrooms:      2,
+	},
+}
+
+const propertyUsage = "Usage:\n" +
+	"/property add name\n" +
+	"/property show name\n" +
+	"/property set name field value\n" +
+	"/property comparable name add|remove|clear [url]\n" +
+	"/property use name|all\n" +
+	"/property delete name\n" +
+	"Fields: bedrooms, double_bedrooms, single_bedrooms, bathrooms, pool, notes, " + marketFields
+
+// scopedProperties returns the properties the chat's commands apply to
+func (h *Handler) scopedProperties(chatID int) ([]models.Property, error) {
+	properties, err := h.mongoClient.GetProperties()
+	if err != nil {
+		return nil, err
+	}
+	if len(properties) == 0 {
+		return []models.Property{defaultProperty}, nil
+	}
+
+	settings, err := h.mongoClient.GetChatSettings(chatID)
+	if err != nil {
+		return nil, err
+	}
+	if settings.ActiveProperty == "" || settings.ActiveProperty == models.AllProperties {
+		return properties, nil
+	}
+
+	for _, property := range properties {
+		if property.Name == settings.ActiveProperty {
+			return []models.Property{property}, nil
+		}
+	}
+	return nil, fmt.Errorf("active property %q no longer exists, select another one with /property use", settings.ActiveProperty)
+}
+
+// handleProperties lists every property and marks the chat's selection
+func (h *Handler) handleProperties(inv *invocation) error {
+	properties, err := h.mongoClient.GetProperties()
+	if err != nil {
+		return h.fail(inv, "Error: ", err)
+	}
+	if len(properties) == 0 {
+		return h.transport.SendMessage(inv.ChatID, "No properties configured. Add one with /property add name.")
+	}
+
+	settings, err := h.mongoClient.GetChatSettings(inv.ChatID)
+	if err != nil {
+		return h.fail(inv, "Error: ", err)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Properties:\n")
+	for _, property := range properties {
+		marker := "  "
+		if property.Name == settings.ActiveProperty {
+			marker = "* "
+		}
+		sb.WriteString(fmt.Sprintf("%s%s (%s)\n", marker, property.Name, property.Market.Location))
+	}
+	if settings.ActiveProperty == "" || settings.ActiveProperty == models.AllProperties {
+		sb.WriteString("\nCommands apply to all properties.")
+	}
+	return h.transport.SendMessage(inv.ChatID, sb.String())
+}
+
+// handleProperty manages a single property and the chat's active selection
+func (h *Handler) handleProperty(inv *invocation, args []string) error {
+	if len(args) < 2 {
+		return h.usage(inv, propertyUsage)
+	}
+
+	action, name := args[0], args[1]
+
+	// Changing properties affects every chat, so only admins may do it
+	if slices.Contains([]string{"add", "set", "comparable", "delete"}, action) && !inv.Local && !h.cfg.IsAdmin(inv.UserID) {
+		return h.usage(inv, "Adding, changing and deleting properties is only available to admins.")
+	}
+
+	switch action {
+	case "add":
+		if name == models.AllProperties || name == defaultProperty.Name {
+			return h.transport.SendMessage(inv.ChatID, "The name "+name+" is reserved.")
+		}
+		property := &models.Property{Name: name, Profile: defaultProperty.Profile}
+		if err := h.mongoClient.CreateProperty(property); err != nil {
+			return h.fail(inv, "Error: ", err)
+		}
+		return h.transport.SendMessage(inv.ChatID, "Property "+name+" added. Configure it with /property set.")
+
+	case "show":
+		property, err := h.mongoClient.GetProperty(name)
+		if err != nil {
+			return h.fail(inv, "Error: ", err)
+		}
+		return h.transport.SendMessage(inv.ChatID, formatProperty(property))
+
+	case "set":
+		if len(args) < 4 {
+			return h.usage(inv, propertyUsage)
+		}
+		property, err := h.mongoClient.GetProperty(name)
+		if err != nil {
+			return h.fail(inv, "Error: ", err)
+		}
+		if err := setPropertyField(property, args[2], strings.Join(args[3:], " ")); err != nil {
+			return h.fail(inv, "Error: ", err)
+		}
+		if err := h.mongoClient.UpdateProperty(property); err != nil {
+			return h.fail(inv, "Error: ", err)
+		}
+		return h.transport.SendMessage(inv.ChatID, formatProperty(property))
+
+	case "comparable":
+		if len(args) < 3 {
+			return h.usage(inv, propertyUsage)
+		}
+		property, err := h.mongoClient.GetProperty(name)
+		if err != nil {
+			return h.fail(inv, "Error: ", err)
+		}
+		if err := updateComparables(property, args[2], args[3:]); err != nil {
+			return h.fail(inv, "Error: ", err)
+		}
+		if err := h.mongoClient.UpdateProperty(property); err != nil {
+			return h.fail(inv, "Error: ", err)
+		}
+		return h.transport.SendMessage(inv.ChatID, fmt.Sprintf("Property %s has %d comparable listings.", name, len(property.Comparables)))
+
+	case "use":
+		if name != models.AllProperties {
+			if _, err := h.mongoClient.GetProperty(name); err != nil {
+				return h.fail(inv, "Error: ", err)
+			}
+		}
+		if err := h.mongoClient.SetActiveProperty(inv.ChatID, name); err != nil {
+			return h.fail(inv, "Error: ", err)
+		}
+		if name == models.AllProperties {
+			return h.transport.SendMessage(inv.ChatID, "Commands now apply to all properties.")
+		}
+		return h.transport.SendMessage(inv.ChatID, "Active property: "+name)
+
+	case "delete":
+		if err := h.mongoClient.DeleteProperty(name); err != nil {
+			return h.fail(inv, "Error: ", err)
+		}
+		// Chats scoped to the deleted property fall back to every property
+		cleared, err := h.mongoClient.ClearActiveProperty(name)
+		if err != nil {
+			return h.fail(inv, "Property "+name+" deleted, but chats using it could not be reset. Error: ", err)
+		}
+		if cleared > 0 {
+			return h.transport.SendMessage(inv.ChatID, fmt.Sprintf("Property %s deleted. %d chats using it now apply to all properties.", name, cleared))
+		}
+		return h.transport.SendMessage(inv.ChatID, "Property "+name+" deleted.")
+
+	default:
+		return h.usage(inv, propertyUsage)
+	}
+}
+
+// setPropertyField updates a single profile or market field from its text value
+func setPropertyField(property *models.Property, field, value string) error {
+	if slices.Contains(strings.Split(marketFields, ", "), field) {
+		return setMarketField(&property.Market, field, value)
+	}
+
+	switch field {
+	case "notes":
+		property.Profile.Notes = value
+		return nil
+	case "pool":
+		pool, err := strconv.ParseBool(strings.NewReplacer("yes", "true", "no", "false").Replace(value))
+		if err != nil {
+			return fmt.Errorf("invalid pool value %q, use yes or no", value)
+		}
+		property.Profile.Pool = pool
+		return nil
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil || number < 0 {
+		return fmt.Errorf("invalid %s value %q", field, value)
+	}
+
+	switch field {
+	case "bedrooms":
+		property.Profile.Bedrooms = number
+	case "double_bedrooms":
+		property.Profile.DoubleBedrooms = number
+	case "single_bedrooms":
+		property.Profile.SingleBedrooms = number
+	case "bathrooms":
+		property.Profile.Bathrooms = number
+	default:
+		return errors.New("unknown field " + field)
+	}
+	return nil
+}
+
+// marketFields lists the search filters that can be set on a property or a single scrape
+const marketFields = "location, guests, min_price, max_price, property_type, min_bedrooms"
+
+// setMarketField parses and sets one search filter of a market
+func setMarketField(market *models.Market, field, value string) error {
+	switch field {
+	case "location":
+		market.Location = value
+		return nil
+	case "property_type":
+		market.PropertyType = value
+		return nil
+	case "min_price", "max_price":
+		price, err := strconv.ParseFloat(value, 64)
+		if err != nil || price < 0 {
+			return fmt.Errorf("invalid %s value %q", field, value)
+		}
+		if field == "min_price" {
+			market.MinPrice = price
+		} else {
+			market.MaxPrice = price
+		}
+		return nil
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil || number < 0 {
+		return fmt.Errorf("invalid %s value %q", field, value)
+	}
+
+	switch field {
+	case "guests":
+		market.Guests = number
+	case "min_bedrooms":
+		market.MinBedrooms = number
+	default:
+		return errors.New("unknown field " + field)
+	}
+	return nil
+}
+
+// extractMarketFilters removes field=value arguments, checking that each is a valid filter
+func extractMarketFilters(args []string) ([]string, []string, error) {
+	remaining, filters := []string{}, []string{}
+	for _, arg := range args {
+		field, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			remaining = append(remaining, arg)
+			continue
+		}
+		if !slices.Contains(strings.Split(marketFields, ", "), field) {
+			return nil, nil, errors.New("unknown filter " + field)
+		}
+		if err := setMarketField(&models.Market{}, field, value); err != nil {
+			return nil, nil, err
+		}
+		filters = append(filters, arg)
+	}
+	return remaining, filters, nil
+}
+
+// applyMarketFilters returns the market with the checked field=value filters applied
+func applyMarketFilters(market models.Market, filters []string) models.Market {
+	for _, filter := range filters {
+		field, value, _ := strings.Cut(filter, "=")
+		setMarketField(&market, field, value)
+	}
+	return market
+}
+
+// updateComparables applies an add, remove or clear operation to the comparable set
+func updateComparables(property *models.Property, operation string, urls []string) error {
+	switch operation {
+	case "add":
+		if len(urls) == 0 {
+			return errors.New("missing listing url")
+		}
+		for _, url := range urls {
+			if !slices.Contains(property.Comparables, url) {
+				property.Comparables = append(property.Comparables, url)
+			}
+		}
+	case "remove":
+		if len(urls) == 0 {
+			return errors.New("missing listing url")
+		}
+		kept := property.Comparables[:0]
+		for _, comparable := range property.Comparables {
+			if !slices.Contains(urls, comparable) {
+				kept = append(kept, comparable)
+			}
+		}
+		property.Comparables = kept
+	case "clear":
+		property.Comparables = nil
+	default:
+		return errors.New("unknown comparable operation " + operation)
+	}
+	return nil
+}
+
+// formatProperty formats a property into a readable message
+func formatProperty(property *models.Property) string {
+	return fmt.Sprintf("Property: %s\nProfile: %s\nMarket: %s\nComparable listings: %d",
+		property.Name, property.Profile.Describe(), property.Market.Describe(), len(property.Comparables))
+}
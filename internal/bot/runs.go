@@ -0,0 +1,102 @@
+package bot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zenha/oliveiras/internal/models"
+)
+
+// maxRuns caps how many runs /runs lists
+const maxRuns = 50
+
+// handleRuns lists the most recent scrape runs and their outcomes
+func (h *Handler) handleRuns(inv *invocation, args []string) error {
+	count := 10
+	if len(args) > 0 {
+		parsed, err := strconv.Atoi(args[0])
+		if err != nil || parsed <= 0 || len(args) > 1 {
+			return h.usage(inv, fmt.Sprintf("Usage: /runs [count], at most %d", maxRuns))
+		}
+		count = min(parsed, maxRuns)
+	}
+
+	runs, err := h.mongoClient.GetRecentScrapeRuns(count)
+	if err != nil {
+		return h.fail(inv, "Error: ", err)
+	}
+	if len(runs) == 0 {
+		return h.transport.SendMessage(inv.ChatID, "No scrape runs yet.")
+	}
+
+	sections := make([]string, 0, len(runs))
+	for _, run := range runs {
+		sections = append(sections, formatRun(run))
+	}
+	return h.transport.SendMessage(inv.ChatID, fmt.Sprintf("Last %d scrape runs:\n\n%s", len(runs), strings.Join(sections, "\n\n")))
+}
+
+// formatRun renders a run as its status line, query, trigger and one entry per platform
+func formatRun(run models.ScrapeRun) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s %s", run.StartedAt.Local().Format("2006-01-02 15:04"), strings.ToUpper(run.Status)))
+	if run.Suspicious {
+		sb.WriteString(" SUSPICIOUS")
+	}
+	if !run.EndedAt.IsZero() {
+		sb.WriteString(fmt.Sprintf(" in %s", run.EndedAt.Sub(run.StartedAt).Round(time.Second)))
+	}
+	sb.WriteString(fmt.Sprintf(", %d listings", run.Listings))
+	if run.Rejected > 0 {
+		sb.WriteString(fmt.Sprintf(", %d rejected", run.Rejected))
+	}
+
+	sb.WriteString(fmt.Sprintf("\n%s to %s, %s", run.Query.StartDate, run.Query.EndDate, run.Query.Market.Describe()))
+	if run.TriggeredBy != "" {
+		sb.WriteString("\n" + run.TriggeredBy)
+	}
+
+	results := make([]string, 0, len(run.Platforms))
+	for _, platform := range run.Platforms {
+		results = append(results, formatRunResult(run, platform))
+	}
+	sb.WriteString("\n" + strings.Join(results, ", "))
+	sb.WriteString("\nRun " + run.ID.Hex())
+	return sb.String()
+}
+
+// formatRunResult renders the outcome of one platform of a run, e.g. "Booking failed: blocked"
+func formatRunResult(run models.ScrapeRun, platform string) string {
+	for _, result := range run.Results {
+		if result.Platform != platform {
+			continue
+		}
+		if result.Error != "" {
+			message := fmt.Sprintf("%s failed: %s", platformLabel(platform), result.Error)
+			if result.Attempts > 1 {
+				message += fmt.Sprintf(" (after %d attempts)", result.Attempts)
+			}
+			return message
+		}
+		message := fmt.Sprintf("%s %d", platformLabel(platform), result.Listings)
+		if result.Skipped > 0 {
+			message += fmt.Sprintf(" (%d malformed records skipped)", result.Skipped)
+		}
+		if result.Cached {
+			message += " cached"
+		} else if !result.SourceRun.IsZero() {
+			message += " shared"
+		}
+		if result.Usage != nil {
+			message += fmt.Sprintf(" (CPU %s, %d MB)", (result.Usage.User + result.Usage.System).Round(100*time.Millisecond), result.Usage.MaxRSSBytes/(1024*1024))
+		}
+		return message
+	}
+
+	if run.Status == models.RunRunning {
+		return platformLabel(platform) + " running"
+	}
+	return platformLabel(platform) + " not finished"
+}
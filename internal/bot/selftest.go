@@ -0,0 +1,90 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/zenha/oliveiras/internal/selftest"
+)
+
+// handleSelfTest runs every parser against its bundled page, replays the bundled scraper
+// output of every platform and, with --live, runs a tiny real scrape of every platform as a
+// dry run. Live scrapes are reserved for admins.
+func (h *Handler) handleSelfTest(inv *invocation, args []string) error {
+	args, live := extractFlag(args, "--live")
+	if len(args) != 0 {
+		return h.usage(inv, "Usage: /selftest [--live]")
+	}
+	if live && !inv.Local && !h.cfg.IsAdmin(inv.UserID) {
+		return h.usage(inv, "Live self-tests are only available to admins.")
+	}
+
+	if live {
+		if err := h.transport.SendMessage(inv.ChatID, "Running the self-test with a live scrape, this can take a few minutes."); err != nil {
+			return err
+		}
+	}
+
+	results := h.runSelfTest(inv.ctx, live)
+	if failed := selftest.Failed(results); len(failed) > 0 {
+		inv.err = fmt.Errorf("%d self-test checks failed", len(failed))
+	}
+	return h.transport.SendMessage(inv.ChatID, formatSelfTest(results))
+}
+
+// RunStartupSelfTest runs the self-test when the bot starts, logging the report and sending
+// it to the admins when a check fails
+func (h *Handler) RunStartupSelfTest(ctx context.Context, live bool) {
+	results := h.runSelfTest(ctx, live)
+	report := formatSelfTest(results)
+	log.Println(report)
+
+	if len(selftest.Failed(results)) == 0 {
+		return
+	}
+	for _, adminID := range h.cfg.AdminUserIDs {
+		if err := h.transport.SendMessage(adminID, "Startup "+report); err != nil {
+			log.Println("Failed to send self-test report:", err)
+		}
+	}
+}
+
+// runSelfTest runs the fixture checks and, when live is set, the live checks
+func (h *Handler) runSelfTest(ctx context.Context, live bool) []selftest.Result {
+	results := selftest.Fixtures()
+	if live {
+		results = append(results, selftest.Live(ctx, h.scheduler)...)
+	}
+	return results
+}
+
+// formatSelfTest renders one line per platform and mode
+func formatSelfTest(results []selftest.Result) string {
+	var sb strings.Builder
+	sb.WriteString("Self-test:\n")
+	for _, result := range results {
+		status := "OK"
+		if !result.OK() {
+			status = "FAIL"
+		}
+		line := fmt.Sprintf("%s %s (%s): %d listings, %d/%d fields",
+			status, platformLabel(result.Platform), result.Mode, result.Listings, len(result.Fields), selftest.TotalFields())
+		if len(result.Missing) > 0 {
+			line += ", missing " + strings.Join(result.Missing, ", ")
+		}
+		if result.Err != nil {
+			line += " - " + result.Err.Error()
+		}
+		sb.WriteString(line + "\n")
+	}
+
+	failed := len(selftest.Failed(results))
+	if failed == 0 {
+		sb.WriteString("\nAll checks passed.")
+	} else {
+		sb.WriteString(fmt.Sprintf("\n%d checks failed.", failed))
+	}
+	return sb.String()
+}
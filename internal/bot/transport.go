@@ -0,0 +1,21 @@
+package bot
+
+import (
+	"context"
+
+	"github.com/zenha/oliveiras/internal/models"
+)
+
+// Transport carries messages between users and the handler. The Telegram webhook and
+// the local terminal REPL both implement it, so every command runs the same way on each.
+type Transport interface {
+	// Receive delivers incoming messages to handle until ctx is done or the input ends.
+	// The context passed to handle lives as long as the transport, not the single request.
+	Receive(ctx context.Context, handle func(context.Context, models.Message) error) error
+	// SendMessage sends a text reply to a chat
+	SendMessage(chatID int, text string) error
+	// SendMessageWithButtons sends a text reply with buttons whose callback data is a command
+	SendMessageWithButtons(chatID int, text string, buttons []models.InlineKeyboardButton) error
+	// SendDocument sends a file to a chat
+	SendDocument(chatID int, filename string, data []byte, caption string) error
+}
@@ -0,0 +1,129 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/zenha/oliveiras/internal/models"
+)
+
+// LocalChatID is the chat ID used for every message typed in the terminal
+const LocalChatID = 1
+
+// REPL is an interactive terminal transport for running bot commands locally
+type REPL struct {
+	in          io.Reader
+	out         io.Writer
+	documentDir string
+
+	mu sync.Mutex
+}
+
+// NewREPL creates a terminal transport. Documents sent to the chat are written to documentDir.
+func NewREPL(in io.Reader, out io.Writer, documentDir string) *REPL {
+	return &REPL{
+		in:          in,
+		out:         out,
+		documentDir: documentDir,
+	}
+}
+
+// Receive reads one command per line until ctx is done or the input ends
+func (r *REPL) Receive(ctx context.Context, handle func(context.Context, models.Message) error) error {
+	// done stops the reader when Receive returns. A reader blocked on input is released
+	// by closing the input when it can be closed; otherwise it exits after the next line.
+	done := make(chan struct{})
+	defer close(done)
+	if closer, ok := r.in.(io.Closer); ok {
+		go func() {
+			<-done
+			closer.Close()
+		}()
+	}
+
+	lines := make(chan string)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(r.in)
+		for scanner.Scan() {
+			select {
+			case lines <- scanner.Text():
+			case <-done:
+				return
+			}
+		}
+		errs <- scanner.Err()
+	}()
+
+	userName := os.Getenv("USER")
+	r.print("Type a command such as /scrape 2025-01-14 2025-01-16, or exit to quit.\n")
+	for {
+		r.print("> ")
+		select {
+		case <-ctx.Done():
+			return nil
+		case line, ok := <-lines:
+			if !ok {
+				return <-errs
+			}
+			line = strings.TrimSpace(line)
+			if line == "exit" || line == "quit" {
+				return nil
+			}
+			if line == "" {
+				continue
+			}
+			err := handle(ctx, models.Message{
+				ChatID:   LocalChatID,
+				UserID:   os.Getuid(),
+				UserName: userName,
+				Text:     line,
+				Local:    true,
+			})
+			if err != nil {
+				r.print("Error: " + err.Error() + "\n")
+			}
+		}
+	}
+}
+
+// SendMessage prints a reply
+func (r *REPL) SendMessage(chatID int, text string) error {
+	r.print(text + "\n\n")
+	return nil
+}
+
+// SendMessageWithButtons prints a reply followed by the command behind each button
+func (r *REPL) SendMessageWithButtons(chatID int, text string, buttons []models.InlineKeyboardButton) error {
+	var sb strings.Builder
+	sb.WriteString(text + "\n")
+	for _, button := range buttons {
+		sb.WriteString(fmt.Sprintf("[%s] %s\n", button.Text, button.CallbackData))
+	}
+	r.print(sb.String() + "\n")
+	return nil
+}
+
+// SendDocument writes the document to the document directory and prints its path
+func (r *REPL) SendDocument(chatID int, filename string, data []byte, caption string) error {
+	path := filepath.Join(r.documentDir, filepath.Base(filename))
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return err
+	}
+	r.print(fmt.Sprintf("Document saved to %s\n%s\n\n", path, caption))
+	return nil
+}
+
+// print writes to the output, serializing replies from background commands
+func (r *REPL) print(text string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fmt.Fprint(r.out, text)
+}
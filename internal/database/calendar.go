@@ -0,0 +1,57 @@
+package database
+
+import (
+	"context"
+
+	"github.com/zenha/oliveiras/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// UpsertNightPrices stores per-night prices, replacing the price of the same platform, night,
+// stay length and market
+func (c *Client) UpsertNightPrices(prices []models.NightPrice) error {
+	collection := c.client.Database("oliveiras").Collection("calendar")
+
+	if len(prices) == 0 {
+		return nil
+	}
+
+	writes := make([]mongo.WriteModel, 0, len(prices))
+	for _, price := range prices {
+		filter := bson.M{
+			"platform":    price.Platform,
+			"night":       price.Night,
+			"stay_nights": price.StayNights,
+			"market":      price.Market,
+		}
+		writes = append(writes, mongo.NewReplaceOneModel().SetFilter(filter).SetReplacement(price).SetUpsert(true))
+	}
+
+	_, err := collection.BulkWrite(context.TODO(), writes, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+// GetNightPrices retrieves the stored per-night prices of a market between two nights, inclusive
+func (c *Client) GetNightPrices(startNight, endNight string, stayNights int, market models.Market) ([]models.NightPrice, error) {
+	collection := c.client.Database("oliveiras").Collection("calendar")
+
+	filter := bson.M{
+		"night":       bson.M{"$gte": startNight, "$lte": endNight},
+		"stay_nights": stayNights,
+		"market":      market,
+	}
+
+	cursor, err := collection.Find(context.TODO(), filter, options.Find().SetSort(bson.D{{Key: "night", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.TODO())
+
+	var results []models.NightPrice
+	if err := cursor.All(context.TODO(), &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
@@ -0,0 +1,34 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/zenha/oliveiras/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// InsertCommandLog stores a command invocation in the audit log
+func (c *Client) InsertCommandLog(entry *models.CommandLog) error {
+	collection := c.client.Database("oliveiras").Collection("command_log")
+
+	_, err := collection.InsertOne(context.TODO(), entry)
+	return err
+}
+
+// GetCommandLogsSince retrieves every command invocation started after the given time
+func (c *Client) GetCommandLogsSince(since time.Time) ([]models.CommandLog, error) {
+	collection := c.client.Database("oliveiras").Collection("command_log")
+
+	cursor, err := collection.Find(context.TODO(), bson.M{"started_at": bson.M{"$gte": since}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.TODO())
+
+	var results []models.CommandLog
+	if err := cursor.All(context.TODO(), &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
@@ -0,0 +1,311 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/zenha/oliveiras/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// insertedAtLayout is the inserted_at format shared with the documents the scraper used to
+// write. The time is always written in UTC, as readers treat it as UTC.
+const insertedAtLayout = "2006-01-02T15:04:05.999999"
+
+// formatInsertedAt formats a time as an inserted_at value
+func formatInsertedAt(t time.Time) string {
+	return t.UTC().Format(insertedAtLayout)
+}
+
+// listingCollections maps platforms to the collections holding their listings
+var listingCollections = map[string]string{
+	"airbnb":  "airbnb",
+	"booking": "booking",
+	"vrbo":    "vrbo",
+}
+
+// UpsertListings stores scraped listings in the platform's collection together with the query
+// and run that found them, replacing the listing the same query found with the same URL and
+// stay dates. Listings stored before they carried a query key are taken over by the first
+// query that finds them again; other queries' listings are left alone.
+func (c *Client) UpsertListings(platform string, query models.SearchQuery, runID primitive.ObjectID, listings []models.ScrapedListing, insertedAt time.Time) error {
+	collectionName, ok := listingCollections[platform]
+	if !ok {
+		return fmt.Errorf("no collection for platform %q", platform)
+	}
+	collection := c.client.Database("oliveiras").Collection(collectionName)
+
+	if len(listings) == 0 {
+		return nil
+	}
+
+	writes := make([]mongo.WriteModel, 0, len(listings))
+	for _, listing := range listings {
+		filter := bson.M{
+			"url":        listing.URL,
+			"start_date": listing.StartDate,
+			"end_date":   listing.EndDate,
+			"$or": bson.A{
+				bson.M{"query_key": query.Key()},
+				bson.M{"query_key": bson.M{"$exists": false}},
+			},
+		}
+		update := bson.M{"$set": listingDocument(platform, query, runID, listing, insertedAt)}
+		writes = append(writes, mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(update).SetUpsert(true))
+	}
+
+	_, err := collection.BulkWrite(context.TODO(), writes, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+// GetListings retrieves the platform's listings of every stay within a date range. A market
+// with a location or guest count keeps only the listings found by searches of that market, and
+// listings stored before they carried their query, whose market is unknown. A non-zero since
+// leaves out listings inserted before it.
+func (c *Client) GetListings(platform string, market models.Market, startDate, endDate string, since time.Time) ([]models.StoredListing, error) {
+	collectionName, ok := listingCollections[platform]
+	if !ok {
+		return nil, fmt.Errorf("no collection for platform %q", platform)
+	}
+	collection := c.client.Database("oliveiras").Collection(collectionName)
+
+	filter := bson.M{
+		"start_date": bson.M{"$gte": startDate},
+		"end_date":   bson.M{"$lte": endDate},
+	}
+	marketFilter := bson.M{}
+	if market.Location != "" {
+		marketFilter["query.location"] = market.Location
+	}
+	if market.Guests > 0 {
+		marketFilter["query.guests"] = market.Guests
+	}
+	if len(marketFilter) > 0 {
+		filter["$or"] = bson.A{
+			marketFilter,
+			bson.M{"query": bson.M{"$exists": false}},
+		}
+	}
+	if !since.IsZero() {
+		filter["inserted_at"] = bson.M{"$gte": formatInsertedAt(since)}
+	}
+	return findStoredListings(collection, platform, filter)
+}
+
+// GetQueryListingsSince retrieves the listings a query found for exactly the given stay that
+// were inserted at or after the given time, leaving out those of other markets
+func (c *Client) GetQueryListingsSince(platform, queryKey, startDate, endDate string, since time.Time) ([]models.ScrapedListing, error) {
+	collectionName, ok := listingCollections[platform]
+	if !ok {
+		return nil, fmt.Errorf("no collection for platform %q", platform)
+	}
+	collection := c.client.Database("oliveiras").Collection(collectionName)
+
+	filter := bson.M{
+		"query_key":   queryKey,
+		"start_date":  startDate,
+		"end_date":    endDate,
+		"inserted_at": bson.M{"$gte": formatInsertedAt(since)},
+	}
+	return findListings(collection, platform, filter)
+}
+
+// GetRunListings retrieves the listings a scrape run stored for exactly the given stay
+func (c *Client) GetRunListings(platform string, runID primitive.ObjectID, startDate, endDate string) ([]models.ScrapedListing, error) {
+	collectionName, ok := listingCollections[platform]
+	if !ok {
+		return nil, fmt.Errorf("no collection for platform %q", platform)
+	}
+	collection := c.client.Database("oliveiras").Collection(collectionName)
+
+	filter := bson.M{
+		"run_id":     runID,
+		"start_date": startDate,
+		"end_date":   endDate,
+	}
+	return findListings(collection, platform, filter)
+}
+
+// findListings decodes the platform's listings matching a filter
+func findListings(collection *mongo.Collection, platform string, filter bson.M) ([]models.ScrapedListing, error) {
+	stored, err := findStoredListings(collection, platform, filter)
+	if err != nil {
+		return nil, err
+	}
+	var results []models.ScrapedListing
+	for _, listing := range stored {
+		results = append(results, listing.ScrapedListing)
+	}
+	return results, nil
+}
+
+// findStoredListings decodes the platform's listings matching a filter with their insertion time
+func findStoredListings(collection *mongo.Collection, platform string, filter bson.M) ([]models.StoredListing, error) {
+	cursor, err := collection.Find(context.TODO(), filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.TODO())
+
+	var results []models.StoredListing
+	for cursor.Next(context.TODO()) {
+		listing, err := decodeListing(platform, cursor)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, listing)
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// listingDocument builds the stored shape of a listing, which differs per platform
+func listingDocument(platform string, query models.SearchQuery, runID primitive.ObjectID, listing models.ScrapedListing, insertedAt time.Time) bson.M {
+	doc := bson.M{
+		"query":       query,
+		"query_key":   query.Key(),
+		"timestamp":   insertedAt.UTC().Format(time.RFC3339),
+		"url":         listing.URL,
+		"start_date":  listing.StartDate,
+		"end_date":    listing.EndDate,
+		"inserted_at": formatInsertedAt(insertedAt),
+	}
+	if !runID.IsZero() {
+		doc["run_id"] = runID
+	}
+	if listing.PriceDetails != nil {
+		doc["price_details"] = listing.PriceDetails
+	}
+
+	switch platform {
+	case "airbnb":
+		doc["listing"] = models.Listing{
+			Name:             listing.Name,
+			Price:            listing.Price,
+			TotalPrice:       listing.TotalPrice,
+			Rating:           listing.Rating,
+			Reviews:          listing.Reviews,
+			BedConfiguration: listing.BedConfiguration,
+		}
+	case "vrbo":
+		doc["name"] = listing.Name
+		doc["price"] = listing.Price
+		doc["total_price"] = listing.TotalPrice
+		doc["rating"] = listing.Rating
+		doc["reviews"] = listing.Reviews
+		doc["bed_configuration"] = listing.BedConfiguration
+	default:
+		doc["name"] = listing.Name
+		doc["price"] = listing.Price
+		doc["total_price"] = listing.TotalPrice
+		doc["rating"] = strconv.FormatFloat(listing.Rating, 'f', -1, 64)
+		doc["bed_configuration"] = listing.BedConfiguration
+	}
+	return doc
+}
+
+// decodeListing converts a stored listing back into the platform-neutral shape
+func decodeListing(platform string, cursor *mongo.Cursor) (models.StoredListing, error) {
+	switch platform {
+	case "airbnb":
+		var data models.AirbnbData
+		if err := cursor.Decode(&data); err != nil {
+			return models.StoredListing{}, err
+		}
+		return models.StoredListing{
+			ScrapedListing: models.ScrapedListing{
+				URL:              data.URL,
+				StartDate:        data.StartDate,
+				EndDate:          data.EndDate,
+				Name:             data.Listing.Name,
+				Price:            data.Listing.Price,
+				TotalPrice:       data.Listing.TotalPrice,
+				Rating:           data.Listing.Rating,
+				Reviews:          data.Listing.Reviews,
+				BedConfiguration: data.Listing.BedConfiguration,
+				PriceDetails:     data.PriceDetails,
+			},
+			InsertedAt: data.InsertedAt,
+		}, nil
+	case "vrbo":
+		var data models.VrboData
+		if err := cursor.Decode(&data); err != nil {
+			return models.StoredListing{}, err
+		}
+		return models.StoredListing{
+			ScrapedListing: models.ScrapedListing{
+				URL:              data.URL,
+				StartDate:        data.StartDate,
+				EndDate:          data.EndDate,
+				Name:             data.Name,
+				Price:            data.Price,
+				TotalPrice:       data.TotalPrice,
+				Rating:           data.Rating,
+				Reviews:          data.Reviews,
+				BedConfiguration: data.BedConfiguration,
+				PriceDetails:     data.PriceDetails,
+			},
+			InsertedAt: data.InsertedAt,
+		}, nil
+	default:
+		// Older documents store the rating either as text or as a number
+		var raw bson.M
+		if err := cursor.Decode(&raw); err != nil {
+			return models.StoredListing{}, err
+		}
+		return models.StoredListing{
+			ScrapedListing: models.ScrapedListing{
+				URL:              getString(raw, "url"),
+				StartDate:        getString(raw, "start_date"),
+				EndDate:          getString(raw, "end_date"),
+				Name:             getString(raw, "name"),
+				Price:            getFloat(raw, "price"),
+				TotalPrice:       getFloat(raw, "total_price"),
+				Rating:           getFloat(raw, "rating"),
+				BedConfiguration: getString(raw, "bed_configuration"),
+				PriceDetails:     getPriceDetails(raw, "price_details"),
+			},
+			InsertedAt: getString(raw, "inserted_at"),
+		}, nil
+	}
+}
+
+// getPriceDetails reads a stored price breakdown from a map, or nil when there is none
+func getPriceDetails(m map[string]interface{}, key string) *models.PriceDetails {
+	value, ok := m[key]
+	if !ok || value == nil {
+		return nil
+	}
+	encoded, err := bson.Marshal(value)
+	if err != nil {
+		return nil
+	}
+	var details models.PriceDetails
+	if err := bson.Unmarshal(encoded, &details); err != nil {
+		return nil
+	}
+	return &details
+}
+
+// getFloat reads a numeric value from a map, accepting numbers stored as text
+func getFloat(m map[string]interface{}, key string) float64 {
+	switch v := m[key].(type) {
+	case float64:
+		return v
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case string:
+		parsed, _ := strconv.ParseFloat(v, 64)
+		return parsed
+	}
+	return 0
+}
@@ -0,0 +1,137 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/zenha/oliveiras/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrPropertyNotFound is returned when no property matches the given name
+var ErrPropertyNotFound = errors.New("property not found")
+
+// GetProperties retrieves every property ordered by name
+func (c *Client) GetProperties() ([]models.Property, error) {
+	collection := c.client.Database("oliveiras").Collection("properties")
+
+	cursor, err := collection.Find(context.TODO(), bson.M{}, options.Find().SetSort(bson.M{"name": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.TODO())
+
+	var results []models.Property
+	if err := cursor.All(context.TODO(), &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// GetProperty retrieves a property by name
+func (c *Client) GetProperty(name string) (*models.Property, error) {
+	collection := c.client.Database("oliveiras").Collection("properties")
+
+	var property models.Property
+	err := collection.FindOne(context.TODO(), bson.M{"name": name}).Decode(&property)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrPropertyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &property, nil
+}
+
+// CreateProperty stores a new property, rejecting duplicate names
+func (c *Client) CreateProperty(property *models.Property) error {
+	collection := c.client.Database("oliveiras").Collection("properties")
+
+	count, err := collection.CountDocuments(context.TODO(), bson.M{"name": property.Name})
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("property %q already exists", property.Name)
+	}
+
+	property.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	result, err := collection.InsertOne(context.TODO(), property)
+	if err != nil {
+		return err
+	}
+	if id, ok := result.InsertedID.(primitive.ObjectID); ok {
+		property.ID = id
+	}
+	return nil
+}
+
+// UpdateProperty replaces the stored property with the same name
+func (c *Client) UpdateProperty(property *models.Property) error {
+	collection := c.client.Database("oliveiras").Collection("properties")
+
+	result, err := collection.ReplaceOne(context.TODO(), bson.M{"name": property.Name}, property)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrPropertyNotFound
+	}
+	return nil
+}
+
+// DeleteProperty removes a property by name
+func (c *Client) DeleteProperty(name string) error {
+	collection := c.client.Database("oliveiras").Collection("properties")
+
+	result, err := collection.DeleteOne(context.TODO(), bson.M{"name": name})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrPropertyNotFound
+	}
+	return nil
+}
+
+// ClearActiveProperty points every chat whose active property is the given one back to all
+// properties, returning how many chats were changed
+func (c *Client) ClearActiveProperty(name string) (int64, error) {
+	collection := c.client.Database("oliveiras").Collection("chat_settings")
+
+	result, err := collection.UpdateMany(context.TODO(),
+		bson.M{"active_property": name},
+		bson.M{"$set": bson.M{"active_property": models.AllProperties}})
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// GetChatSettings retrieves the settings for a chat, returning defaults when none are stored
+func (c *Client) GetChatSettings(chatID int) (*models.ChatSettings, error) {
+	collection := c.client.Database("oliveiras").Collection("chat_settings")
+
+	settings := models.ChatSettings{ChatID: chatID}
+	err := collection.FindOne(context.TODO(), bson.M{"chat_id": chatID}).Decode(&settings)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// SetActiveProperty stores the active property selection for a chat
+func (c *Client) SetActiveProperty(chatID int, name string) error {
+	collection := c.client.Database("oliveiras").Collection("chat_settings")
+
+	_, err := collection.UpdateOne(context.TODO(),
+		bson.M{"chat_id": chatID},
+		bson.M{"$set": bson.M{"active_property": name}},
+		options.Update().SetUpsert(true))
+	return err
+}
@@ -0,0 +1,76 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/zenha/oliveiras/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// InsertScrapeRun stores a scrape run when it starts
+func (c *Client) InsertScrapeRun(run *models.ScrapeRun) error {
+	collection := c.client.Database("oliveiras").Collection("scrape_runs")
+
+	_, err := collection.InsertOne(context.TODO(), run)
+	return err
+}
+
+// UpdateScrapeRun replaces a stored scrape run with its current state
+func (c *Client) UpdateScrapeRun(run *models.ScrapeRun) error {
+	collection := c.client.Database("oliveiras").Collection("scrape_runs")
+
+	_, err := collection.ReplaceOne(context.TODO(), bson.M{"_id": run.ID}, run)
+	return err
+}
+
+// MarkInterruptedRuns marks every run still recorded as running as interrupted, ending it
+// now, and returns how many were marked. Only call it when no scrape can be running.
+func (c *Client) MarkInterruptedRuns() (int64, error) {
+	collection := c.client.Database("oliveiras").Collection("scrape_runs")
+
+	result, err := collection.UpdateMany(context.TODO(),
+		bson.M{"status": models.RunRunning},
+		bson.M{"$set": bson.M{"status": models.RunInterrupted, "ended_at": time.Now()}})
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// GetScrapeRun retrieves a scrape run by id, or nil when there is none
+func (c *Client) GetScrapeRun(id primitive.ObjectID) (*models.ScrapeRun, error) {
+	collection := c.client.Database("oliveiras").Collection("scrape_runs")
+
+	var run models.ScrapeRun
+	err := collection.FindOne(context.TODO(), bson.M{"_id": id}).Decode(&run)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// GetRecentScrapeRuns retrieves the most recently started scrape runs, newest first
+func (c *Client) GetRecentScrapeRuns(limit int) ([]models.ScrapeRun, error) {
+	collection := c.client.Database("oliveiras").Collection("scrape_runs")
+
+	opts := options.Find().SetSort(bson.D{{Key: "started_at", Value: -1}}).SetLimit(int64(limit))
+	cursor, err := collection.Find(context.TODO(), bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.TODO())
+
+	var results []models.ScrapeRun
+	if err := cursor.All(context.TODO(), &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
@@ -0,0 +1,94 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/zenha/oliveiras/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// InsertScrapeRecord stores a successful platform scrape
+func (c *Client) InsertScrapeRecord(record *models.ScrapeRecord) error {
+	collection := c.client.Database("oliveiras").Collection("scrapes")
+
+	_, err := collection.InsertOne(context.TODO(), record)
+	return err
+}
+
+// GetLatestScrapeRecord retrieves the most recent scrape of the platform for the query key
+// that was not suspicious, or nil when there is none
+func (c *Client) GetLatestScrapeRecord(platform, queryKey string) (*models.ScrapeRecord, error) {
+	collection := c.client.Database("oliveiras").Collection("scrapes")
+
+	filter := bson.M{"platform": platform, "query_key": queryKey, "suspicious": bson.M{"$ne": true}}
+	opts := options.FindOne().SetSort(bson.D{{Key: "started_at", Value: -1}})
+
+	var record models.ScrapeRecord
+	err := collection.FindOne(context.TODO(), filter, opts).Decode(&record)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// GetRecentScrapeRecords retrieves up to limit of the most recent scrapes of the platform for
+// the query key that were not suspicious, newest first
+func (c *Client) GetRecentScrapeRecords(platform, queryKey string, limit int) ([]models.ScrapeRecord, error) {
+	collection := c.client.Database("oliveiras").Collection("scrapes")
+
+	filter := bson.M{"platform": platform, "query_key": queryKey, "suspicious": bson.M{"$ne": true}}
+	opts := options.Find().SetSort(bson.D{{Key: "started_at", Value: -1}}).SetLimit(int64(limit))
+
+	cursor, err := collection.Find(context.TODO(), filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.TODO())
+
+	var records []models.ScrapeRecord
+	if err := cursor.All(context.TODO(), &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// GetArchivedScrapeRecords retrieves the scrapes with archived output started at or after
+// since, oldest first. An empty platform matches every platform.
+func (c *Client) GetArchivedScrapeRecords(platform string, since time.Time) ([]models.ScrapeRecord, error) {
+	collection := c.client.Database("oliveiras").Collection("scrapes")
+
+	filter := bson.M{
+		"archive":    bson.M{"$exists": true},
+		"started_at": bson.M{"$gte": since},
+	}
+	if platform != "" {
+		filter["platform"] = platform
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "started_at", Value: 1}})
+
+	cursor, err := collection.Find(context.TODO(), filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.TODO())
+
+	var records []models.ScrapeRecord
+	if err := cursor.All(context.TODO(), &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// UpdateScrapeRecord replaces a stored scrape with its current state
+func (c *Client) UpdateScrapeRecord(record *models.ScrapeRecord) error {
+	collection := c.client.Database("oliveiras").Collection("scrapes")
+
+	_, err := collection.ReplaceOne(context.TODO(), bson.M{"_id": record.ID}, record)
+	return err
+}
@@ -18,14 +18,14 @@ func NewClient(apiKey string) (*genai.Client, error) {
 	return client, err
 }
 
-// Call the GenerateContent method
-func GenerateContent(client *genai.Client, prompt string) (string, error) {
+// Call the GenerateContent method, briefing the model with the given rent house information
+func GenerateContent(client *genai.Client, houseInfo string, prompt string) (string, error) {
 	config := genai.GenerateContentConfig{
 		SystemInstruction: &genai.Content{
 			Role: "system",
 			Parts: []*genai.Part{
 				{
-					Text: "You are a very talented and experiences hotel manager. Your task is according to the information provided regarding the listings from the same location where your rent house is, provide an appropriate price for each of the dates provided on the listings. Respond with JUST the date: price on each line. >>> Example: 2025-01-14: 112.99 >>> Rent House Information: 3 rooms. 2 double bed rooms. 1 dual single bed room. 2 bathrooms. No pool.",
+					Text: "You are a very talented and experiences hotel manager. Your task is according to the information provided regarding the listings from the same location where your rent house is, provide an appropriate price for each of the dates provided on the listings. Respond with JUST the date: price on each line. >>> Example: 2025-01-14: 112.99 >>> Rent House Information: " + houseInfo,
 				},
 			},
 		},
@@ -54,22 +54,12 @@ func GenerateContent(client *genai.Client, prompt string) (string, error) {
 	return response, nil
 }
 
-func PrepareAirbnbPrompt(airbnbListings []models.AirbnbData) string {
+// PreparePrompt lists the stored listings of any platform for the pricing model
+func PreparePrompt(listings []models.StoredListing) string {
 	var prompt string
-	for _, listing := range airbnbListings {
-		formattedString := fmt.Sprintf("%s to %s: %.2f > Info: %s - ", listing.StartDate, listing.EndDate, listing.Listing.Price, listing.Listing.BedConfiguration)
-		prompt += formattedString
-	}
-	// log.Println("Airbnb Prompt: ", prompt)
-	return prompt
-}
-
-func PrepareBookingPrompt(bookingListings []models.BookingData) string {
-	var prompt string
-	for _, listing := range bookingListings {
+	for _, listing := range listings {
 		formattedString := fmt.Sprintf("%s to %s: %.2f > Info: %s - ", listing.StartDate, listing.EndDate, listing.Price, listing.BedConfiguration)
 		prompt += formattedString
 	}
-	// log.Println("Booking Prompt: ", prompt)
 	return prompt
 }
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// NightPrice is the market price of one night on one platform, taken from stays of
+// StayNights nights starting that night
+type NightPrice struct {
+	Platform     string    `json:"platform" bson:"platform"`
+	Night        string    `json:"night" bson:"night"`
+	StayNights   int       `json:"stay_nights" bson:"stay_nights"`
+	Market       Market    `json:"market" bson:"market"`
+	AveragePrice float64   `json:"average_price" bson:"average_price"`
+	LowestPrice  float64   `json:"lowest_price" bson:"lowest_price"`
+	HighestPrice float64   `json:"highest_price" bson:"highest_price"`
+	Listings     int       `json:"listings" bson:"listings"`
+	ScrapedAt    time.Time `json:"scraped_at" bson:"scraped_at"`
+}
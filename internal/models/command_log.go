@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Command outcomes recorded in the audit log
+const (
+	OutcomeOK    = "ok"
+	OutcomeUsage = "usage"
+	OutcomeError = "error"
+)
+
+// CommandLog represents one command invocation in the audit log
+type CommandLog struct {
+	UserID     int       `json:"user_id" bson:"user_id"`
+	UserName   string    `json:"user_name" bson:"user_name"`
+	ChatID     int       `json:"chat_id" bson:"chat_id"`
+	Command    string    `json:"command" bson:"command"`
+	Args       []string  `json:"args" bson:"args"`
+	StartedAt  time.Time `json:"started_at" bson:"started_at"`
+	DurationMs int64     `json:"duration_ms" bson:"duration_ms"`
+	Outcome    string    `json:"outcome" bson:"outcome"`
+	Error      string    `json:"error,omitempty" bson:"error,omitempty"`
+}
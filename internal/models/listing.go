@@ -13,17 +13,23 @@ type AirbnbData struct {
 	EndDate    string  `json:"end_date" bson:"end_date"`
 	Listing    Listing `json:"listing" bson:"listing"`
 	InsertedAt string  `json:"inserted_at" bson:"inserted_at"`
+	// RunID is the scrape run that last stored the listing
+	RunID        primitive.ObjectID `json:"run_id,omitempty" bson:"run_id,omitempty"`
+	PriceDetails *PriceDetails      `json:"price_details,omitempty" bson:"price_details,omitempty"`
 }
 
 // Listing represents the core listing data
 type Listing struct {
 	Name             string  `json:"name" bson:"name"`
 	Price            float64 `json:"price" bson:"price"`
+	TotalPrice       float64 `json:"total_price,omitempty" bson:"total_price,omitempty"`
 	Rating           float64 `json:"rating" bson:"rating"`
+	Reviews          int     `json:"reviews,omitempty" bson:"reviews,omitempty"`
 	BedConfiguration string  `json:"bed_configuration" bson:"bed_configuration"`
 }
 
-// BookingData represents a Booking listing
+// BookingData represents a Booking listing. Price is nightly; listings stored before prices
+// were normalized hold the stay price.
 type BookingData struct {
 	ID               primitive.ObjectID `json:"_id" bson:"_id"`
 	Timestamp        string             `json:"timestamp" bson:"timestamp"`
@@ -32,9 +38,30 @@ type BookingData struct {
 	EndDate          string             `json:"end_date" bson:"end_date"`
 	Name             string             `json:"name" bson:"name"`
 	Price            float64            `json:"price" bson:"price"`
+	TotalPrice       float64            `json:"total_price,omitempty" bson:"total_price,omitempty"`
 	Rating           string             `json:"rating" bson:"rating"`
 	BedConfiguration string             `json:"bed_configuration" bson:"bed_configuration"`
 	InsertedAt       string             `json:"inserted_at" bson:"inserted_at"`
+	RunID            primitive.ObjectID `json:"run_id,omitempty" bson:"run_id,omitempty"`
+	PriceDetails     *PriceDetails      `json:"price_details,omitempty" bson:"price_details,omitempty"`
+}
+
+// VrboData represents a Vrbo listing. The rating uses Vrbo's 10-point scale.
+type VrboData struct {
+	ID               primitive.ObjectID `json:"_id" bson:"_id"`
+	Timestamp        string             `json:"timestamp" bson:"timestamp"`
+	URL              string             `json:"url" bson:"url"`
+	StartDate        string             `json:"start_date" bson:"start_date"`
+	EndDate          string             `json:"end_date" bson:"end_date"`
+	Name             string             `json:"name" bson:"name"`
+	Price            float64            `json:"price" bson:"price"`
+	TotalPrice       float64            `json:"total_price,omitempty" bson:"total_price,omitempty"`
+	Rating           float64            `json:"rating" bson:"rating"`
+	Reviews          int                `json:"reviews,omitempty" bson:"reviews,omitempty"`
+	BedConfiguration string             `json:"bed_configuration" bson:"bed_configuration"`
+	InsertedAt       string             `json:"inserted_at" bson:"inserted_at"`
+	RunID            primitive.ObjectID `json:"run_id,omitempty" bson:"run_id,omitempty"`
+	PriceDetails     *PriceDetails      `json:"price_details,omitempty" bson:"price_details,omitempty"`
 }
 
 // ListingAnalysis represents analyzed data for listings
@@ -44,3 +71,30 @@ type ListingAnalysis struct {
 	LowestPrice   float64 `json:"lowest_price"`
 	TotalListings int     `json:"total_listings"`
 }
+
+// ScrapedListing is a platform-neutral listing as reported by the scraper. Price is the nightly
+// price on every platform.
+type ScrapedListing struct {
+	URL       string  `json:"url" bson:"url"`
+	StartDate string  `json:"start_date" bson:"start_date"`
+	EndDate   string  `json:"end_date" bson:"end_date"`
+	Name      string  `json:"name" bson:"name"`
+	Price     float64 `json:"price" bson:"price"`
+	// TotalPrice is what the guest pays for the whole stay; a listing may report it
+	// instead of the nightly price, which is then derived from it
+	TotalPrice       float64 `json:"total_price,omitempty" bson:"total_price,omitempty"`
+	Rating           float64 `json:"rating" bson:"rating"`
+	Reviews          int     `json:"reviews" bson:"reviews"`
+	BedConfiguration string  `json:"bed_configuration" bson:"bed_configuration"`
+	// PriceText is the price as the platform shows it, parsed into PriceDetails when the
+	// listing is validated
+	PriceText    string        `json:"price_text,omitempty" bson:"-"`
+	PriceDetails *PriceDetails `json:"price_details,omitempty" bson:"price_details,omitempty"`
+}
+
+// StoredListing is a listing read back from its platform's collection
+type StoredListing struct {
+	ScrapedListing
+	// InsertedAt is when the listing was last stored, as written to inserted_at
+	InsertedAt string
+}
@@ -0,0 +1,11 @@
+package models
+
+// Message is an incoming chat message addressed to the bot, independent of its transport
+type Message struct {
+	ChatID   int
+	UserID   int
+	UserName string
+	Text     string
+	// Local marks messages typed by an operator on the machine running the bot
+	Local bool
+}
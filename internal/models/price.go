@@ -0,0 +1,12 @@
+package models
+
+// PriceDetails is the breakdown of a listing's price parsed from the text the platform shows.
+// Nightly excludes the fees and taxes listed separately; Total is everything the guest pays.
+type PriceDetails struct {
+	Currency    string  `json:"currency,omitempty" bson:"currency,omitempty"`
+	Nightly     float64 `json:"nightly,omitempty" bson:"nightly,omitempty"`
+	Total       float64 `json:"total,omitempty" bson:"total,omitempty"`
+	CleaningFee float64 `json:"cleaning_fee,omitempty" bson:"cleaning_fee,omitempty"`
+	ServiceFee  float64 `json:"service_fee,omitempty" bson:"service_fee,omitempty"`
+	Taxes       float64 `json:"taxes,omitempty" bson:"taxes,omitempty"`
+}
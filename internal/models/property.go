@@ -0,0 +1,105 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AllProperties is the active-property value that scopes commands to every property
+const AllProperties = "all"
+
+// Property represents a rental property managed through the bot
+type Property struct {
+	ID          primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	Name        string             `json:"name" bson:"name"`
+	Profile     PropertyProfile    `json:"profile" bson:"profile"`
+	Market      Market             `json:"market" bson:"market"`
+	Comparables []string           `json:"comparables" bson:"comparables"`
+	CreatedAt   string             `json:"created_at" bson:"created_at"`
+}
+
+// PropertyProfile describes the house itself, used to brief the pricing model
+type PropertyProfile struct {
+	Bedrooms       int    `json:"bedrooms" bson:"bedrooms"`
+	DoubleBedrooms int    `json:"double_bedrooms" bson:"double_bedrooms"`
+	SingleBedrooms int    `json:"single_bedrooms" bson:"single_bedrooms"`
+	Bathrooms      int    `json:"bathrooms" bson:"bathrooms"`
+	Pool           bool   `json:"pool" bson:"pool"`
+	Notes          string `json:"notes" bson:"notes"`
+}
+
+// Market holds the search parameters used to find comparable listings. Zero values leave
+// the choice to the scraper.
+type Market struct {
+	Location     string  `json:"location" bson:"location"`
+	Guests       int     `json:"guests" bson:"guests"`
+	MinPrice     float64 `json:"min_price,omitempty" bson:"min_price,omitempty"`
+	MaxPrice     float64 `json:"max_price,omitempty" bson:"max_price,omitempty"`
+	PropertyType string  `json:"property_type,omitempty" bson:"property_type,omitempty"`
+	MinBedrooms  int     `json:"min_bedrooms,omitempty" bson:"min_bedrooms,omitempty"`
+}
+
+// ChatSettings stores per-chat preferences
+type ChatSettings struct {
+	ChatID         int    `json:"chat_id" bson:"chat_id"`
+	ActiveProperty string `json:"active_property" bson:"active_property"`
+}
+
+// Describe returns a plain-text description of the property profile
+func (p PropertyProfile) Describe() string {
+	parts := []string{fmt.Sprintf("%d rooms.", p.Bedrooms)}
+	if p.DoubleBedrooms > 0 {
+		parts = append(parts, fmt.Sprintf("%d double bed rooms.", p.DoubleBedrooms))
+	}
+	if p.SingleBedrooms > 0 {
+		parts = append(parts, fmt.Sprintf("%d dual single bed room.", p.SingleBedrooms))
+	}
+	parts = append(parts, fmt.Sprintf("%d bathrooms.", p.Bathrooms))
+	if p.Pool {
+		parts = append(parts, "Pool.")
+	} else {
+		parts = append(parts, "No pool.")
+	}
+	if p.Notes != "" {
+		parts = append(parts, p.Notes)
+	}
+	return strings.Join(parts, " ")
+}
+
+// Describe returns a plain-text description of the market filters
+func (m Market) Describe() string {
+	parts := []string{}
+	if m.Location != "" {
+		parts = append(parts, m.Location)
+	}
+	if m.Guests > 0 {
+		parts = append(parts, fmt.Sprintf("%d guests", m.Guests))
+	}
+	switch {
+	case m.MinPrice > 0 && m.MaxPrice > 0:
+		parts = append(parts, fmt.Sprintf("%.0f-%.0f per night", m.MinPrice, m.MaxPrice))
+	case m.MinPrice > 0:
+		parts = append(parts, fmt.Sprintf("from %.0f per night", m.MinPrice))
+	case m.MaxPrice > 0:
+		parts = append(parts, fmt.Sprintf("up to %.0f per night", m.MaxPrice))
+	}
+	if m.PropertyType != "" {
+		parts = append(parts, m.PropertyType)
+	}
+	if m.MinBedrooms > 0 {
+		parts = append(parts, fmt.Sprintf("%d+ bedrooms", m.MinBedrooms))
+	}
+	if len(parts) == 0 {
+		return "(scraper default)"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// IsComparable reports whether a listing URL belongs to the property's comparable set.
+// An empty comparable set accepts every listing.
+func (p Property) IsComparable(url string) bool {
+	return len(p.Comparables) == 0 || slices.Contains(p.Comparables, url)
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Scrape run statuses
+const (
+	RunRunning   = "running"
+	RunSucceeded = "succeeded"
+	RunPartial   = "partial"
+	RunFailed    = "failed"
+	RunCancelled = "cancelled"
+	// RunInterrupted marks runs that were still running when the bot stopped
+	RunInterrupted = "interrupted"
+)
+
+// ScrapeRun is one requested scrape of a query over one or more platforms
+type ScrapeRun struct {
+	ID          primitive.ObjectID `json:"_id" bson:"_id"`
+	Query       SearchQuery        `json:"query" bson:"query"`
+	Platforms   []string           `json:"platforms" bson:"platforms"`
+	TriggeredBy string             `json:"triggered_by" bson:"triggered_by"`
+	StartedAt   time.Time          `json:"started_at" bson:"started_at"`
+	EndedAt     time.Time          `json:"ended_at,omitempty" bson:"ended_at,omitempty"`
+	Status      string             `json:"status" bson:"status"`
+	Listings    int                `json:"listings" bson:"listings"`
+	Rejected    int                `json:"rejected" bson:"rejected"`
+	Results     []RunResult        `json:"results" bson:"results"`
+	// Suspicious is set when a platform's output looked wrong and was not stored
+	Suspicious bool `json:"suspicious,omitempty" bson:"suspicious,omitempty"`
+}
+
+// RunResult is the outcome of one platform in a scrape run
+type RunResult struct {
+	Platform string `json:"platform" bson:"platform"`
+	Listings int    `json:"listings" bson:"listings"`
+	Rejected int    `json:"rejected" bson:"rejected"`
+	// Skipped counts the malformed scraper records left out
+	Skipped  int  `json:"skipped,omitempty" bson:"skipped,omitempty"`
+	Attempts int  `json:"attempts" bson:"attempts"`
+	Cached   bool `json:"cached,omitempty" bson:"cached,omitempty"`
+	// SourceRun is the run that stored the listings when they came from another run,
+	// through the cache or an identical scrape that was already running
+	SourceRun primitive.ObjectID `json:"source_run,omitempty" bson:"source_run,omitempty"`
+	Error     string             `json:"error,omitempty" bson:"error,omitempty"`
+	Anomalies []string           `json:"anomalies,omitempty" bson:"anomalies,omitempty"`
+	// Usage is what the scraper consumed for the platform, when it was scraped
+	Usage *ResourceUsage `json:"usage,omitempty" bson:"usage,omitempty"`
+}
+
+// ResourceUsage is what a scraper process consumed
+type ResourceUsage struct {
+	User        time.Duration `json:"user" bson:"user"`
+	System      time.Duration `json:"system" bson:"system"`
+	MaxRSSBytes int64         `json:"max_rss_bytes" bson:"max_rss_bytes"`
+}
+
+func (u ResourceUsage) String() string {
+	return fmt.Sprintf("%s user, %s system, max RSS %d MB", u.User.Round(time.Millisecond), u.System.Round(time.Millisecond), u.MaxRSSBytes/(1024*1024))
+}
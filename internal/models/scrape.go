@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ScrapeRecord notes a successful scrape of one platform for a query, so recent results
+// can be reused instead of scraping again
+type ScrapeRecord struct {
+	ID        primitive.ObjectID `json:"_id" bson:"_id,omitempty"`
+	RunID     primitive.ObjectID `json:"run_id,omitempty" bson:"run_id,omitempty"`
+	Platform  string             `json:"platform" bson:"platform"`
+	QueryKey  string             `json:"query_key" bson:"query_key"`
+	Query     SearchQuery        `json:"query" bson:"query"`
+	StartedAt time.Time          `json:"started_at" bson:"started_at"`
+	Listings  int                `json:"listings" bson:"listings"`
+	// Snapshot holds the URL and price of every listing found, to compare with the next scrape
+	Snapshot []ListingPrice `json:"snapshot" bson:"snapshot"`
+	// Suspicious scrapes looked wrong compared with recent ones; their listings were not stored
+	Suspicious bool     `json:"suspicious,omitempty" bson:"suspicious,omitempty"`
+	Anomalies  []string `json:"anomalies,omitempty" bson:"anomalies,omitempty"`
+	// Archive is the raw scraper output kept for reprocessing, relative to the archive directory
+	Archive string `json:"archive,omitempty" bson:"archive,omitempty"`
+}
+
+// ListingPrice is the price of one listing in a scrape snapshot
+type ListingPrice struct {
+	URL   string  `json:"url" bson:"url"`
+	Price float64 `json:"price" bson:"price"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "fmt"
+
+// SearchQuery is a search for listings on any platform: the stay plus the market filters.
+// It is sent to the scraper as JSON and stored with every listing it finds.
+type SearchQuery struct {
+	StartDate string `json:"start_date" bson:"start_date"`
+	EndDate   string `json:"end_date" bson:"end_date"`
+	Market    `bson:",inline"`
+}
+
+// Key identifies identical queries
+func (q SearchQuery) Key() string {
+	return fmt.Sprintf("%s|%s|%s|%d|%g|%g|%s|%d", q.StartDate, q.EndDate, q.Location, q.Guests,
+		q.MinPrice, q.MaxPrice, q.PropertyType, q.MinBedrooms)
+}
@@ -19,4 +19,28 @@ type TelegramUpdate struct {
 		Date int    `json:"date"`
 		Text string `json:"text"`
 	} `json:"message"`
+	CallbackQuery *CallbackQuery `json:"callback_query"`
+}
+
+// CallbackQuery represents a press on an inline keyboard button
+type CallbackQuery struct {
+	ID   string `json:"id"`
+	From struct {
+		ID        int    `json:"id"`
+		FirstName string `json:"first_name"`
+	} `json:"from"`
+	Message struct {
+		MessageID int `json:"message_id"`
+		Chat      struct {
+			ID int `json:"id"`
+		} `json:"chat"`
+	} `json:"message"`
+	Data string `json:"data"`
+}
+
+// InlineKeyboardButton represents a button attached to a message.
+// CallbackData is limited by Telegram to 64 bytes.
+type InlineKeyboardButton struct {
+	Text         string `json:"text"`
+	CallbackData string `json:"callback_data"`
 }
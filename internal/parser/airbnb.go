@@ -0,0 +1,180 @@
+package parser
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/zenha/oliveiras/internal/models"
+	"github.com/zenha/oliveiras/internal/price"
+)
+
+// airbnbRatingPattern matches localized ratings such as "4.92 (85)"
+var airbnbRatingPattern = regexp.MustCompile(`([\d.,]+)\s*\((\d[\d,.]*)\)`)
+
+// airbnbRoomURL is the public URL of a listing
+const airbnbRoomURL = "https://www.airbnb.com/rooms/"
+
+// ParseAirbnb extracts the listings of an Airbnb search results page from the JSON the page
+// embeds. startDate and endDate are the searched stay, used to derive nightly and total
+// prices. Results without a name, identifier or price are skipped; a page where every
+// result is skipped is an error, as the page layout most likely changed.
+func ParseAirbnb(r io.Reader, startDate, endDate string) ([]models.AirbnbData, error) {
+	page, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	documents := embeddedJSON(string(page))
+	if len(documents) == 0 {
+		return nil, errors.New("no embedded search data found in Airbnb page")
+	}
+
+	nights := stayNights(startDate, endDate)
+	seen := make(map[string]bool)
+	results := []models.AirbnbData{}
+	skipped := 0
+	for _, document := range documents {
+		walkObjects(document, func(item map[string]interface{}) {
+			listing := object(item, "listing")
+			pricing := object(item, "pricingQuote")
+			if listing == nil || pricing == nil {
+				return
+			}
+
+			data, err := parseAirbnbResult(listing, pricing, nights)
+			if err != nil {
+				log.Println("Skipping Airbnb result:", err)
+				skipped++
+				return
+			}
+			if seen[data.URL] {
+				return
+			}
+			seen[data.URL] = true
+
+			data.StartDate = startDate
+			data.EndDate = endDate
+			results = append(results, *data)
+		})
+	}
+	if len(results) == 0 && skipped > 0 {
+		return nil, fmt.Errorf("none of the %d Airbnb results could be parsed", skipped)
+	}
+	return results, nil
+}
+
+// parseAirbnbResult converts one search result into AirbnbData
+func parseAirbnbResult(listing, pricing map[string]interface{}, nights int) (*models.AirbnbData, error) {
+	id := airbnbListingID(listing)
+	if id == "" {
+		return nil, errors.New("result without listing id")
+	}
+
+	name := str(listing, "name")
+	if name == "" {
+		name = str(listing, "title")
+	}
+	if name == "" {
+		return nil, fmt.Errorf("listing %s without name", id)
+	}
+
+	details, err := airbnbPrices(pricing, nights)
+	if err != nil {
+		return nil, fmt.Errorf("listing %s: %v", id, err)
+	}
+
+	rating, reviews := airbnbRating(listing)
+
+	data := &models.AirbnbData{
+		URL: airbnbRoomURL + id,
+		Listing: models.Listing{
+			Name:             name,
+			Price:            details.Nightly,
+			TotalPrice:       details.Total,
+			Rating:           rating,
+			Reviews:          reviews,
+			BedConfiguration: airbnbBedConfiguration(listing),
+		},
+		PriceDetails: &details,
+	}
+	return data, nil
+}
+
+// airbnbListingID returns the numeric listing ID. Newer pages use opaque IDs that are the
+// base64 encoding of "StayListing:<id>".
+func airbnbListingID(listing map[string]interface{}) string {
+	switch id := listing["id"].(type) {
+	case float64:
+		return strconv.FormatInt(int64(id), 10)
+	case string:
+		if decoded, err := base64.StdEncoding.DecodeString(id); err == nil {
+			if _, numeric, ok := strings.Cut(string(decoded), ":"); ok {
+				return numeric
+			}
+		}
+		return id
+	}
+	return ""
+}
+
+// airbnbPrices returns the price breakdown of a result. The primary price line is either a
+// nightly rate ("night") or the stay price ("for 5 nights", "total"); the secondary line,
+// when present, holds the total.
+func airbnbPrices(pricing map[string]interface{}, nights int) (models.PriceDetails, error) {
+	primary := object(pricing, "structuredStayDisplayPrice", "primaryLine")
+	secondary := object(pricing, "structuredStayDisplayPrice", "secondaryLine")
+
+	priceText := str(primary, "discountedPrice")
+	if priceText == "" {
+		priceText = str(primary, "price")
+	}
+	if priceText == "" {
+		rate := object(pricing, "rate")
+		if amount := number(rate, "amount"); amount > 0 {
+			return models.PriceDetails{Currency: str(rate, "currency"), Nightly: amount, Total: amount * float64(nights)}, nil
+		}
+		return models.PriceDetails{}, errors.New("no price")
+	}
+
+	details, err := price.Parse(priceText+" "+str(primary, "qualifier")+"\n"+str(secondary, "price"), nights)
+	if err != nil {
+		return models.PriceDetails{}, err
+	}
+	if details.Nightly <= 0 {
+		return models.PriceDetails{}, fmt.Errorf("invalid price %q", priceText)
+	}
+	return details, nil
+}
+
+// airbnbRating returns the average rating and review count of a listing; new listings have neither
+func airbnbRating(listing map[string]interface{}) (float64, int) {
+	if match := airbnbRatingPattern.FindStringSubmatch(str(listing, "avgRatingLocalized")); match != nil {
+		rating, _ := price.ParseAmount(match[1])
+		reviews, _ := strconv.Atoi(strings.NewReplacer(",", "", ".", "").Replace(match[2]))
+		return rating.Value, reviews
+	}
+	return number(listing, "avgRating"), int(number(listing, "reviewsCount"))
+}
+
+// airbnbBedConfiguration joins the room and bed lines shown under the listing title
+func airbnbBedConfiguration(listing map[string]interface{}) string {
+	lines, _ := object(listing, "structuredContent")["primaryLine"].([]interface{})
+	parts := []string{}
+	for _, line := range lines {
+		if entry, ok := line.(map[string]interface{}); ok {
+			if body := str(entry, "body"); body != "" {
+				parts = append(parts, body)
+			}
+		}
+	}
+	if len(parts) == 0 {
+		return str(listing, "subtitle")
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,90 @@
+package parser
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// The stay the fixture pages were saved for
+const (
+	fixtureStartDate = "2025-01-14"
+	fixtureEndDate   = "2025-01-16"
+)
+
+// parsedListing holds the fields the parser tests check on every listing
+type parsedListing struct {
+	name    string
+	url     string
+	price   float64
+	rating  float64
+	reviews int
+}
+
+func TestParseAirbnb(t *testing.T) {
+	tests := []struct {
+		name     string
+		page     string
+		listings []parsedListing
+		err      string
+	}{
+		{
+			name: "search results",
+			page: "fixtures/airbnb_search.html",
+			listings: []parsedListing{
+				{name: "Casa da Oliveira", url: "https://www.airbnb.com/rooms/12345678", price: 125, rating: 4.92, reviews: 85},
+				{name: "Maria's Olive Grove Retreat", url: "https://www.airbnb.com/rooms/87654321", price: 155, rating: 4.8, reviews: 1204},
+				{name: "Sea view loft", url: "https://www.airbnb.com/rooms/55501234", price: 525},
+				{name: "Quinta do Vale", url: "https://www.airbnb.com/rooms/99887766", price: 98.5, rating: 4.67, reviews: 12},
+			},
+		},
+		{
+			name: "page without search data",
+			page: "testdata/airbnb_empty.html",
+			err:  "no embedded search data found",
+		},
+		{
+			name: "every result broken",
+			page: "testdata/airbnb_broken.html",
+			err:  "none of the 2 Airbnb results could be parsed",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			page, err := os.Open(test.page)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer page.Close()
+
+			results, err := ParseAirbnb(page, fixtureStartDate, fixtureEndDate)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("expected error containing %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(results) != len(test.listings) {
+				t.Fatalf("expected %d listings, got %d", len(test.listings), len(results))
+			}
+			for i, want := range test.listings {
+				data := results[i]
+				got := parsedListing{name: data.Listing.Name, url: data.URL, price: data.Listing.Price, rating: data.Listing.Rating, reviews: data.Listing.Reviews}
+				if got != want {
+					t.Errorf("listing %d: expected %+v, got %+v", i, want, got)
+				}
+				if data.Listing.TotalPrice != want.price*2 {
+					t.Errorf("listing %d: expected a total of %.2f for 2 nights, got %.2f", i, want.price*2, data.Listing.TotalPrice)
+				}
+				if data.StartDate != fixtureStartDate || data.EndDate != fixtureEndDate {
+					t.Errorf("listing %d: expected stay %s to %s, got %s to %s", i, fixtureStartDate, fixtureEndDate, data.StartDate, data.EndDate)
+				}
+			}
+		})
+	}
+}
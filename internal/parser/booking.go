@@ -0,0 +1,143 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/zenha/oliveiras/internal/models"
+	"github.com/zenha/oliveiras/internal/price"
+)
+
+// bookingBaseURL resolves relative links of result cards
+const bookingBaseURL = "https://www.booking.com"
+
+// ParseBooking extracts the property cards of a Booking.com search results page. Cards show
+// the stay price, which is spread over the nights so that the price is nightly like on the
+// other platforms; the total keeps what the guest pays for the stay. The rating keeps
+// Booking's 10-point scale. Cards that fail validation are skipped; a page without cards, or
+// where every card is skipped, is an error, as the page layout most likely changed.
+func ParseBooking(r io.Reader, startDate, endDate string) ([]models.BookingData, error) {
+	page, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	cards := splitByAttribute(string(page), `data-testid="property-card"`)
+	if len(cards) == 0 {
+		return nil, errors.New("no property cards found in Booking page")
+	}
+
+	nights := stayNights(startDate, endDate)
+	results := []models.BookingData{}
+	seen := make(map[string]bool)
+	skipped := 0
+	for _, card := range cards {
+		data, err := parseBookingCard(card, nights)
+		if err != nil {
+			log.Println("Skipping Booking card:", err)
+			skipped++
+			continue
+		}
+		if seen[data.URL] {
+			continue
+		}
+		seen[data.URL] = true
+
+		data.StartDate = startDate
+		data.EndDate = endDate
+		results = append(results, *data)
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("none of the %d Booking cards could be parsed", skipped)
+	}
+	return results, nil
+}
+
+// parseBookingCard converts one property card into validated BookingData
+func parseBookingCard(card string, nights int) (*models.BookingData, error) {
+	name := textContent(innerHTML(card, `data-testid="title"`))
+	if name == "" {
+		return nil, errors.New("card without title")
+	}
+
+	link, err := bookingListingURL(attributeValue(card, `data-testid="title-link"`, "href"))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+
+	priceText := textContent(innerHTML(card, `data-testid="price-and-discounted-price"`))
+	if priceText == "" {
+		return nil, fmt.Errorf("%s: no price", name)
+	}
+	// The card's price is the stay price; taxes and charges are shown below it when not included
+	details, err := price.Parse(priceText+"\n"+textContent(innerHTML(card, `data-testid="taxes-and-charges"`)), nights)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	if details.Nightly <= 0 {
+		return nil, fmt.Errorf("%s: invalid price %q", name, priceText)
+	}
+
+	rating, err := bookingRating(card)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+
+	return &models.BookingData{
+		URL:              link,
+		Name:             name,
+		Price:            details.Nightly,
+		TotalPrice:       details.Total,
+		Rating:           rating,
+		BedConfiguration: strings.Join(textLines(innerHTML(card, `data-testid="recommended-units"`)), ", "),
+		PriceDetails:     &details,
+	}, nil
+}
+
+// bookingListingURL resolves a card link and strips the search parameters, so the same
+// property keeps the same URL across searches
+func bookingListingURL(href string) (string, error) {
+	if href == "" {
+		return "", errors.New("no link")
+	}
+	parsed, err := url.Parse(href)
+	if err != nil {
+		return "", err
+	}
+	base, _ := url.Parse(bookingBaseURL)
+	resolved := base.ResolveReference(parsed)
+	resolved.RawQuery = ""
+	resolved.Fragment = ""
+	return resolved.String(), nil
+}
+
+// bookingRating returns the review score as text, e.g. "8.7", or "" for unrated properties.
+// The score is the first number of the score block, which also holds a word and the review count.
+func bookingRating(card string) (string, error) {
+	block := textContent(innerHTML(card, `data-testid="review-score"`))
+	if block == "" {
+		return "", nil
+	}
+
+	var score float64
+	found := false
+	for _, field := range strings.Fields(block) {
+		parsed, err := strconv.ParseFloat(strings.Replace(field, ",", ".", 1), 64)
+		if err == nil {
+			score, found = parsed, true
+			break
+		}
+	}
+	if !found {
+		return "", nil
+	}
+	if score < 0 || score > 10 {
+		return "", fmt.Errorf("rating %.1f outside the 10-point scale", score)
+	}
+	return strconv.FormatFloat(score, 'f', 1, 64), nil
+}
@@ -0,0 +1,84 @@
+package parser
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParseBooking(t *testing.T) {
+	tests := []struct {
+		name     string
+		page     string
+		listings []parsedListing
+		totals   []float64
+		err      string
+	}{
+		{
+			name: "search results",
+			page: "fixtures/booking_search.html",
+			// Cards show the price of the 2-night stay; taxes shown below it are part of the total
+			listings: []parsedListing{
+				{name: "Casa do Mar", url: "https://www.booking.com/hotel/pt/casa-do-mar-ericeira.en-gb.html", price: 123, rating: 8.7},
+				{name: "Quinta das Oliveiras & Spa", url: "https://www.booking.com/hotel/pt/quinta-das-oliveiras.en-gb.html", price: 590, rating: 9.2},
+				{name: "Surf Lodge Ericeira", url: "https://www.booking.com/hotel/pt/surf-lodge-ericeira.en-gb.html", price: 82},
+			},
+			totals: []float64{254, 1180, 164},
+		},
+		{
+			name: "page without cards",
+			page: "testdata/booking_empty.html",
+			err:  "no property cards found",
+		},
+		{
+			name: "every card broken",
+			page: "testdata/booking_broken.html",
+			err:  "none of the 2 Booking cards could be parsed",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			page, err := os.Open(test.page)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer page.Close()
+
+			results, err := ParseBooking(page, fixtureStartDate, fixtureEndDate)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("expected error containing %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(results) != len(test.listings) {
+				t.Fatalf("expected %d listings, got %d", len(test.listings), len(results))
+			}
+			for i, want := range test.listings {
+				data := results[i]
+				rating := 0.0
+				if data.Rating != "" {
+					if rating, err = strconv.ParseFloat(data.Rating, 64); err != nil {
+						t.Fatalf("listing %d: invalid rating %q", i, data.Rating)
+					}
+				}
+				got := parsedListing{name: data.Name, url: data.URL, price: data.Price, rating: rating}
+				if got != want {
+					t.Errorf("listing %d: expected %+v, got %+v", i, want, got)
+				}
+				if data.TotalPrice != test.totals[i] {
+					t.Errorf("listing %d: expected a total of %.2f, got %.2f", i, test.totals[i], data.TotalPrice)
+				}
+				if data.PriceDetails == nil || data.PriceDetails.Nightly != data.Price || data.PriceDetails.Total != data.TotalPrice {
+					t.Errorf("listing %d: price breakdown %+v does not match the price", i, data.PriceDetails)
+				}
+			}
+		})
+	}
+}
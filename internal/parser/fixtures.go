@@ -0,0 +1,10 @@
+package parser
+
+import "embed"
+
+// Fixtures holds the saved search results pages of every platform, named
+// fixtures/<platform>_search.html and saved for a stay from 2025-01-14 to 2025-01-16. They are
+// embedded so the self-test can run the parsers against them.
+//
+//go:embed fixtures/*_search.html
+var Fixtures embed.FS
@@ -0,0 +1,140 @@
+package parser
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	tagPattern        = regexp.MustCompile(`(?s)<[^>]*>`)
+	blockEndPattern   = regexp.MustCompile(`(?i)</(?:div|h\d|p|li)>|<br\s*/?>`)
+	whitespacePattern = regexp.MustCompile(`\s+`)
+)
+
+// splitByAttribute splits a page into the chunks that start at each element carrying the
+// attribute, e.g. every result card of a search page. Each chunk runs until the next one.
+func splitByAttribute(page, attribute string) []string {
+	chunks := []string{}
+	indexes := []int{}
+	for offset := 0; ; {
+		i := strings.Index(page[offset:], attribute)
+		if i < 0 {
+			break
+		}
+		start := strings.LastIndex(page[:offset+i], "<")
+		if start < 0 {
+			start = offset + i
+		}
+		indexes = append(indexes, start)
+		offset += i + len(attribute)
+	}
+	for i, start := range indexes {
+		end := len(page)
+		if i+1 < len(indexes) {
+			end = indexes[i+1]
+		}
+		chunks = append(chunks, page[start:end])
+	}
+	return chunks
+}
+
+// innerHTML returns the content of the first element carrying the attribute, matching
+// nested elements with the same tag name
+func innerHTML(chunk, attribute string) string {
+	i := strings.Index(chunk, attribute)
+	if i < 0 {
+		return ""
+	}
+	start := strings.LastIndex(chunk[:i], "<")
+	if start < 0 {
+		return ""
+	}
+	openEnd := strings.Index(chunk[i:], ">")
+	if openEnd < 0 {
+		return ""
+	}
+	contentStart := i + openEnd + 1
+
+	name := tagName(chunk[start+1:])
+	if name == "" {
+		return ""
+	}
+	open, closing := "<"+name, "</"+name+">"
+
+	depth := 1
+	for pos := contentStart; pos < len(chunk); {
+		nextOpen := strings.Index(chunk[pos:], open)
+		nextClose := strings.Index(chunk[pos:], closing)
+		if nextClose < 0 {
+			return chunk[contentStart:]
+		}
+		if nextOpen >= 0 && nextOpen < nextClose && isTagBoundary(chunk, pos+nextOpen+len(open)) {
+			depth++
+			pos += nextOpen + len(open)
+			continue
+		}
+		depth--
+		if depth == 0 {
+			return chunk[contentStart : pos+nextClose]
+		}
+		pos += nextClose + len(closing)
+	}
+	return chunk[contentStart:]
+}
+
+// attributeValue returns the value of an attribute on the first element carrying marker
+func attributeValue(chunk, marker, attribute string) string {
+	i := strings.Index(chunk, marker)
+	if i < 0 {
+		return ""
+	}
+	start := strings.LastIndex(chunk[:i], "<")
+	end := strings.Index(chunk[i:], ">")
+	if start < 0 || end < 0 {
+		return ""
+	}
+	tag := chunk[start : i+end]
+
+	pattern := regexp.MustCompile(`\s` + regexp.QuoteMeta(attribute) + `="([^"]*)"`)
+	match := pattern.FindStringSubmatch(tag)
+	if match == nil {
+		return ""
+	}
+	return html.UnescapeString(match[1])
+}
+
+// textContent strips tags and entities from an HTML fragment and collapses whitespace
+func textContent(fragment string) string {
+	text := tagPattern.ReplaceAllString(fragment, " ")
+	text = html.UnescapeString(text)
+	return strings.TrimSpace(whitespacePattern.ReplaceAllString(text, " "))
+}
+
+// textLines returns the text of each block element of an HTML fragment, skipping empty ones
+func textLines(fragment string) []string {
+	lines := []string{}
+	for _, block := range blockEndPattern.Split(fragment, -1) {
+		if text := textContent(block); text != "" {
+			lines = append(lines, text)
+		}
+	}
+	return lines
+}
+
+// tagName returns the tag name at the start of s
+func tagName(s string) string {
+	end := strings.IndexAny(s, " \t\n\r/>")
+	if end <= 0 {
+		return ""
+	}
+	return strings.ToLower(s[:end])
+}
+
+// isTagBoundary reports whether the tag name ends at pos, so "<div" does not match "<divider"
+func isTagBoundary(s string, pos int) bool {
+	if pos >= len(s) {
+		return false
+	}
+	return strings.ContainsRune(" \t\n\r/>", rune(s[pos]))
+}
@@ -0,0 +1,89 @@
+package parser
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/zenha/oliveiras/internal/models"
+)
+
+// Platforms that have a parser
+const (
+	PlatformAirbnb  = "airbnb"
+	PlatformBooking = "booking"
+	PlatformVrbo    = "vrbo"
+)
+
+// Listings parses a platform's search results page into the platform-neutral listings the
+// scraper validates and stores
+func Listings(platform string, r io.Reader, startDate, endDate string) ([]models.ScrapedListing, error) {
+	switch platform {
+	case PlatformAirbnb:
+		results, err := ParseAirbnb(r, startDate, endDate)
+		if err != nil {
+			return nil, err
+		}
+		listings := make([]models.ScrapedListing, 0, len(results))
+		for _, data := range results {
+			listings = append(listings, models.ScrapedListing{
+				URL:              data.URL,
+				StartDate:        data.StartDate,
+				EndDate:          data.EndDate,
+				Name:             data.Listing.Name,
+				Price:            data.Listing.Price,
+				TotalPrice:       data.Listing.TotalPrice,
+				Rating:           data.Listing.Rating,
+				Reviews:          data.Listing.Reviews,
+				BedConfiguration: data.Listing.BedConfiguration,
+				PriceDetails:     data.PriceDetails,
+			})
+		}
+		return listings, nil
+	case PlatformBooking:
+		results, err := ParseBooking(r, startDate, endDate)
+		if err != nil {
+			return nil, err
+		}
+		listings := make([]models.ScrapedListing, 0, len(results))
+		for _, data := range results {
+			// The parser writes the rating as text, as Booking listings are stored
+			rating, _ := strconv.ParseFloat(data.Rating, 64)
+			listings = append(listings, models.ScrapedListing{
+				URL:              data.URL,
+				StartDate:        data.StartDate,
+				EndDate:          data.EndDate,
+				Name:             data.Name,
+				Price:            data.Price,
+				TotalPrice:       data.TotalPrice,
+				Rating:           rating,
+				BedConfiguration: data.BedConfiguration,
+				PriceDetails:     data.PriceDetails,
+			})
+		}
+		return listings, nil
+	case PlatformVrbo:
+		results, err := ParseVrbo(r, startDate, endDate)
+		if err != nil {
+			return nil, err
+		}
+		listings := make([]models.ScrapedListing, 0, len(results))
+		for _, data := range results {
+			listings = append(listings, models.ScrapedListing{
+				URL:              data.URL,
+				StartDate:        data.StartDate,
+				EndDate:          data.EndDate,
+				Name:             data.Name,
+				Price:            data.Price,
+				TotalPrice:       data.TotalPrice,
+				Rating:           data.Rating,
+				Reviews:          data.Reviews,
+				BedConfiguration: data.BedConfiguration,
+				PriceDetails:     data.PriceDetails,
+			})
+		}
+		return listings, nil
+	default:
+		return nil, fmt.Errorf("no parser for platform %q", platform)
+	}
+}
@@ -0,0 +1,100 @@
+package parser
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// jsonScriptPattern matches the JSON data blocks embedded in search result pages
+var jsonScriptPattern = regexp.MustCompile(`(?s)<script[^>]*type="application/(?:ld\+)?json"[^>]*>(.*?)</script>`)
+
+// embeddedJSON decodes every JSON script block of a page, skipping blocks that are not valid JSON
+func embeddedJSON(page string) []interface{} {
+	documents := []interface{}{}
+	for _, match := range jsonScriptPattern.FindAllStringSubmatch(page, -1) {
+		var document interface{}
+		if err := json.Unmarshal([]byte(strings.TrimSpace(match[1])), &document); err != nil {
+			continue
+		}
+		documents = append(documents, document)
+	}
+	return documents
+}
+
+// walkObjects calls visit for every JSON object nested in value, depth first
+func walkObjects(value interface{}, visit func(map[string]interface{})) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		visit(v)
+		// Visit keys in order so results keep the same order on every run
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			walkObjects(v[key], visit)
+		}
+	case []interface{}:
+		for _, child := range v {
+			walkObjects(child, visit)
+		}
+	}
+}
+
+// object returns the nested object at the given path, or nil
+func object(m map[string]interface{}, path ...string) map[string]interface{} {
+	for _, key := range path {
+		next, ok := m[key].(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		m = next
+	}
+	return m
+}
+
+// str returns the string at key, or "" when missing or not a string
+func str(m map[string]interface{}, key string) string {
+	if m == nil {
+		return ""
+	}
+	value, _ := m[key].(string)
+	return strings.TrimSpace(value)
+}
+
+// number returns the number at key, accepting numeric strings, or 0
+func number(m map[string]interface{}, key string) float64 {
+	if m == nil {
+		return 0
+	}
+	switch v := m[key].(type) {
+	case float64:
+		return v
+	case string:
+		parsed, _ := strconv.ParseFloat(v, 64)
+		return parsed
+	}
+	return 0
+}
+
+// stayNights returns the number of nights between two dates, at least one
+func stayNights(startDate, endDate string) int {
+	start, err := time.Parse("2006-01-02", startDate)
+	if err != nil {
+		return 1
+	}
+	end, err := time.Parse("2006-01-02", endDate)
+	if err != nil {
+		return 1
+	}
+	nights := int(end.Sub(start).Hours() / 24)
+	if nights < 1 {
+		return 1
+	}
+	return nights
+}
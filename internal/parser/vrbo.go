@@ -0,0 +1,165 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/zenha/oliveiras/internal/models"
+	"github.com/zenha/oliveiras/internal/price"
+)
+
+// vrboBaseURL resolves relative links of result cards
+const vrboBaseURL = "https://www.vrbo.com"
+
+var (
+	// vrboRatingPattern matches ratings such as "9.6 out of 10" or "4.8 out of 5"
+	vrboRatingPattern = regexp.MustCompile(`(\d+(?:[.,]\d+)?)\s*out of\s*(5|10)\b`)
+	// vrboReviewsPattern matches review counts such as "(1,024 reviews)"
+	vrboReviewsPattern = regexp.MustCompile(`\(([\d.,]+)\s*reviews?\)`)
+)
+
+// ParseVrbo extracts the property cards of a Vrbo search results page. Cards show a nightly
+// price, a total for the stay or both; the missing one is derived from the stay length.
+// Ratings on a 5-point scale are converted to Vrbo's current 10-point scale. Cards that fail
+// validation are skipped; a page without cards, or where every card is skipped, is an error,
+// as the page layout most likely changed.
+func ParseVrbo(r io.Reader, startDate, endDate string) ([]models.VrboData, error) {
+	page, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	cards := splitByAttribute(string(page), `data-stid="lodging-card-responsive"`)
+	if len(cards) == 0 {
+		return nil, errors.New("no property cards found in Vrbo page")
+	}
+
+	nights := stayNights(startDate, endDate)
+	results := []models.VrboData{}
+	seen := make(map[string]bool)
+	skipped := 0
+	for _, card := range cards {
+		data, err := parseVrboCard(card, nights)
+		if err != nil {
+			log.Println("Skipping Vrbo card:", err)
+			skipped++
+			continue
+		}
+		if seen[data.URL] {
+			continue
+		}
+		seen[data.URL] = true
+
+		data.StartDate = startDate
+		data.EndDate = endDate
+		results = append(results, *data)
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("none of the %d Vrbo cards could be parsed", skipped)
+	}
+	return results, nil
+}
+
+// parseVrboCard converts one property card into validated VrboData
+func parseVrboCard(card string, nights int) (*models.VrboData, error) {
+	name := textContent(innerHTML(card, `class="uitk-heading`))
+	if name == "" {
+		return nil, errors.New("card without title")
+	}
+
+	link, err := vrboListingURL(attributeValue(card, `data-stid="open-hotel-information"`, "href"))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+
+	details, err := vrboPrices(innerHTML(card, `data-test-id="price-summary"`), nights)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+
+	rating, reviews, err := vrboRating(textContent(card))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+
+	return &models.VrboData{
+		URL:              link,
+		Name:             name,
+		Price:            details.Nightly,
+		TotalPrice:       details.Total,
+		Rating:           rating,
+		Reviews:          reviews,
+		BedConfiguration: vrboBedConfiguration(card),
+		PriceDetails:     &details,
+	}, nil
+}
+
+// vrboListingURL resolves a card link and strips the search parameters, so the same
+// property keeps the same URL across searches
+func vrboListingURL(href string) (string, error) {
+	if href == "" {
+		return "", errors.New("no link")
+	}
+	parsed, err := url.Parse(href)
+	if err != nil {
+		return "", err
+	}
+	base, _ := url.Parse(vrboBaseURL)
+	resolved := base.ResolveReference(parsed)
+	resolved.RawQuery = ""
+	resolved.Fragment = ""
+	return resolved.String(), nil
+}
+
+// vrboPrices reads the price breakdown from the card's price summary lines
+func vrboPrices(summary string, nights int) (models.PriceDetails, error) {
+	details, err := price.Parse(strings.Join(textLines(summary), "\n"), nights)
+	if err != nil {
+		return models.PriceDetails{}, errors.New("no price")
+	}
+	if details.Nightly <= 0 {
+		return models.PriceDetails{}, fmt.Errorf("invalid price %q", textContent(summary))
+	}
+	return details, nil
+}
+
+// vrboRating returns the rating on a 10-point scale and the review count, or zeros for
+// properties without reviews
+func vrboRating(text string) (float64, int, error) {
+	match := vrboRatingPattern.FindStringSubmatch(text)
+	if match == nil {
+		return 0, 0, nil
+	}
+	rating, err := strconv.ParseFloat(strings.Replace(match[1], ",", ".", 1), 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	scale, _ := strconv.ParseFloat(match[2], 64)
+	if rating > scale {
+		return 0, 0, fmt.Errorf("rating %.1f outside the %.0f-point scale", rating, scale)
+	}
+	rating = rating * 10 / scale
+
+	reviews := 0
+	if match := vrboReviewsPattern.FindStringSubmatch(text); match != nil {
+		reviews, _ = strconv.Atoi(strings.NewReplacer(",", "", ".", "").Replace(match[1]))
+	}
+	return rating, reviews, nil
+}
+
+// vrboBedConfiguration returns the card line describing the bedrooms and sleeping places
+func vrboBedConfiguration(card string) string {
+	for _, line := range textLines(card) {
+		lower := strings.ToLower(line)
+		if strings.Contains(lower, "bedroom") || strings.Contains(lower, "sleeps") {
+			return line
+		}
+	}
+	return ""
+}
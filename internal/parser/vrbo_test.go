@@ -0,0 +1,83 @@
+package parser
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseVrbo(t *testing.T) {
+	tests := []struct {
+		name     string
+		page     string
+		listings []parsedListing
+		totals   []float64
+		beds     []string
+		err      string
+	}{
+		{
+			name: "search results",
+			page: "fixtures/vrbo_search.html",
+			// The second Casa da Praia card is a duplicate and Quinta Fechada has no price;
+			// the 5-point rating of Vila Oliveira is converted to the 10-point scale
+			listings: []parsedListing{
+				{name: "Casa da Praia", url: "https://www.vrbo.com/4417186ha", price: 245, rating: 9.6, reviews: 128},
+				{name: "Vila Oliveira & Garden", url: "https://www.vrbo.com/2231908", price: 590, rating: 9.6, reviews: 1024},
+				{name: "Studio Sol", url: "https://www.vrbo.com/9981234ha", price: 89},
+			},
+			totals: []float64{490, 1180, 178},
+			beds:   []string{"3 bedrooms · 2 bathrooms · Sleeps 6", "4 bedrooms · 3 bathrooms · Sleeps 8", "Studio · 1 bathroom · Sleeps 2"},
+		},
+		{
+			name: "page without cards",
+			page: "testdata/vrbo_empty.html",
+			err:  "no property cards found",
+		},
+		{
+			name: "every card broken",
+			page: "testdata/vrbo_broken.html",
+			err:  "none of the 3 Vrbo cards could be parsed",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			page, err := os.Open(test.page)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer page.Close()
+
+			results, err := ParseVrbo(page, fixtureStartDate, fixtureEndDate)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("expected error containing %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(results) != len(test.listings) {
+				t.Fatalf("expected %d listings, got %d", len(test.listings), len(results))
+			}
+			for i, want := range test.listings {
+				data := results[i]
+				got := parsedListing{name: data.Name, url: data.URL, price: data.Price, rating: data.Rating, reviews: data.Reviews}
+				if got != want {
+					t.Errorf("listing %d: expected %+v, got %+v", i, want, got)
+				}
+				if data.TotalPrice != test.totals[i] {
+					t.Errorf("listing %d: expected a total of %.2f, got %.2f", i, test.totals[i], data.TotalPrice)
+				}
+				if data.BedConfiguration != test.beds[i] {
+					t.Errorf("listing %d: expected beds %q, got %q", i, test.beds[i], data.BedConfiguration)
+				}
+				if data.StartDate != fixtureStartDate || data.EndDate != fixtureEndDate {
+					t.Errorf("listing %d: expected stay %s to %s, got %s to %s", i, fixtureStartDate, fixtureEndDate, data.StartDate, data.EndDate)
+				}
+			}
+		})
+	}
+}
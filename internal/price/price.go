@@ -0,0 +1,286 @@
+// Package price parses the price strings shown by rental platforms, such as "€1.234,56",
+// "1,234 €" or "€120 x 3 nights", into amounts and stay price breakdowns.
+package price
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/zenha/oliveiras/internal/models"
+)
+
+// Amount is a money amount and its ISO currency code, empty when the text names none
+type Amount struct {
+	Value    float64
+	Currency string
+}
+
+// numberPattern matches a number with optional thousands groups and decimals. Thousands may be
+// separated by dots, commas or apostrophes; spaces are handled by amountIndexes.
+var numberPattern = regexp.MustCompile(`\d{1,3}(?:[.,'’]\d{3})+(?:[.,]\d+)?|\d+(?:[.,]\d+)?`)
+
+// groupPattern matches a thousands group after a space, with the decimals of the last group
+var groupPattern = regexp.MustCompile(`^\d{3}(?:[.,]\d{1,2})?$`)
+
+// countWordPattern matches a word before a number that makes it a count, as in "for 2"
+var countWordPattern = regexp.MustCompile(`(?i)(?:^|[^\p{L}])(?:for|por|pour)\s*$`)
+
+// multipliedPattern matches a nightly rate times the stay length, e.g. "€120 x 3 nights"
+var multipliedPattern = regexp.MustCompile(`(?i)[x×*]\s*(\d+)\s*(?:nights?|noites?|noches?|nuits?)`)
+
+// countPattern matches what follows a number that counts nights or guests rather than money,
+// as in "2 nights €310" or "3 x €100"
+var countPattern = regexp.MustCompile(`(?i)^\s*(?:[x×]|(?:nights?|noites?|noches?|nuits?)\b)`)
+
+// stayLengthPattern matches a stay length such as "2 nights". Only the plural counts, as
+// "€125 night" is a nightly rate and the price of one night is the same either way.
+var stayLengthPattern = regexp.MustCompile(`(?i)\d+\s*(?:nights|noites|noches|nuits)\b`)
+
+// Keywords of the price parts, matched as whole words so "subtotal" is no total and "taxi"
+// and "private" are no taxes
+var (
+	totalWords    = keywordPattern("total")
+	cleaningWords = keywordPattern("clean", "cleaning", "limpeza", "limpieza", "ménage")
+	serviceWords  = keywordPattern("service", "serviço", "servicio")
+	taxWords      = keywordPattern("tax", "taxes", "imposto", "impostos", "impuesto", "impuestos", "iva", "vat", "charges")
+	nightWords    = keywordPattern("night", "nights", "nightly", "noite", "noites", "noche", "noches", "nuit", "nuits")
+	perNightWords = keywordPattern("per night", "por noite", "por noche", "par nuit")
+	forWords      = keywordPattern("for", "por", "pour")
+)
+
+// currencies maps the symbols and codes found in price strings to ISO codes, longest first
+// so "R$" is not read as "$"
+var currencies = []struct {
+	symbol string
+	code   string
+}{
+	{"R$", "BRL"},
+	{"US$", "USD"},
+	{"EUR", "EUR"},
+	{"USD", "USD"},
+	{"GBP", "GBP"},
+	{"BRL", "BRL"},
+	{"CHF", "CHF"},
+	{"€", "EUR"},
+	{"£", "GBP"},
+	{"$", "USD"},
+}
+
+// ParseAmount extracts the amount from a price string: the first number next to a currency
+// symbol or code, else the first one that does not count nights ("2 nights", "3 x"), else the
+// first number. When only one kind of separator is used, it is read as a thousands separator
+// if it repeats or is followed by exactly three digits, and as the decimal separator
+// otherwise; with both kinds the last one is decimal.
+func ParseAmount(text string) (Amount, error) {
+	text = html.UnescapeString(text)
+	match := pickAmount(text)
+	if match == "" {
+		return Amount{}, fmt.Errorf("no amount in %q", text)
+	}
+
+	lastDot := strings.LastIndex(match, ".")
+	lastComma := strings.LastIndex(match, ",")
+	decimal := -1
+	switch {
+	case lastDot >= 0 && lastComma >= 0:
+		decimal = max(lastDot, lastComma)
+	case lastDot >= 0 && strings.Count(match, ".") == 1 && len(match)-lastDot-1 != 3:
+		decimal = lastDot
+	case lastComma >= 0 && strings.Count(match, ",") == 1 && len(match)-lastComma-1 != 3:
+		decimal = lastComma
+	}
+
+	var sb strings.Builder
+	for i, r := range match {
+		switch {
+		case r >= '0' && r <= '9':
+			sb.WriteRune(r)
+		case i == decimal:
+			sb.WriteRune('.')
+		}
+	}
+	value, err := strconv.ParseFloat(sb.String(), 64)
+	if err != nil {
+		return Amount{}, err
+	}
+	return Amount{Value: value, Currency: Currency(text)}, nil
+}
+
+// pickAmount returns the number of the text that is most likely its amount, or ""
+func pickAmount(text string) string {
+	matches := amountIndexes(text)
+	if len(matches) == 0 {
+		return ""
+	}
+
+	uncounted := ""
+	for _, match := range matches {
+		before, after := text[:match[0]], text[match[1]:]
+		if countPattern.MatchString(after) {
+			continue
+		}
+		if nextToCurrency(before, after) {
+			return text[match[0]:match[1]]
+		}
+		if uncounted == "" {
+			uncounted = text[match[0]:match[1]]
+		}
+	}
+	if uncounted != "" {
+		return uncounted
+	}
+	return text[matches[0][0]:matches[0][1]]
+}
+
+// amountIndexes returns the start and end of each number in the text, joining numbers
+// separated by a single (non-breaking) space into one when the space separates thousands: it is
+// followed by exactly three digits and the number before it has at most three digits and does
+// not follow a count word, so "1 480 €" is one amount and "for 2 100 €" is 2 and 100.
+func amountIndexes(text string) [][]int {
+	var indexes [][]int
+	for _, match := range numberPattern.FindAllStringIndex(text, -1) {
+		if len(indexes) > 0 {
+			last := indexes[len(indexes)-1]
+			gap := text[last[1]:match[0]]
+			if (gap == " " || gap == "\u00a0" || gap == "\u202f") && groupPattern.MatchString(text[match[0]:match[1]]) && isGroup(text, last) {
+				last[1] = match[1]
+				continue
+			}
+		}
+		indexes = append(indexes, match)
+	}
+	return indexes
+}
+
+// isGroup reports whether the number at index can be followed by a space-separated thousands
+// group: its own groups are separated by spaces and it does not count something
+func isGroup(text string, index []int) bool {
+	number := text[index[0]:index[1]]
+	first, _, _ := strings.Cut(strings.NewReplacer("\u00a0", " ", "\u202f", " ").Replace(number), " ")
+	if len(first) > 3 || strings.ContainsAny(number, ".,'’") {
+		return false
+	}
+	return !countWordPattern.MatchString(text[:index[0]])
+}
+
+// nextToCurrency reports whether a currency symbol or code ends the text before a number or
+// starts the text after it, ignoring spaces in between
+func nextToCurrency(before, after string) bool {
+	before = strings.ToUpper(strings.TrimRightFunc(before, unicode.IsSpace))
+	after = strings.ToUpper(strings.TrimLeftFunc(after, unicode.IsSpace))
+	for _, currency := range currencies {
+		if strings.HasSuffix(before, currency.symbol) || strings.HasPrefix(after, currency.symbol) {
+			return true
+		}
+	}
+	return false
+}
+
+// Currency returns the ISO code of the first currency named in the text, or ""
+func Currency(text string) string {
+	upper := strings.ToUpper(text)
+	best, code := -1, ""
+	for _, currency := range currencies {
+		index := strings.Index(upper, currency.symbol)
+		if index >= 0 && (best < 0 || index < best) {
+			best, code = index, currency.code
+		}
+	}
+	return code
+}
+
+// Parse reads a stay price breakdown from the price text of a listing, one part per line or
+// separated by "·" or "|". Parts are recognised by their wording: nightly rates ("per night",
+// "€120 x 3 nights"), totals, cleaning and service fees and taxes. An amount without wording
+// is the stay price before the fees and taxes listed next to it. nights is the stay length
+// used to derive the nightly price from the stay price and the other way round.
+func Parse(text string, nights int) (models.PriceDetails, error) {
+	if nights < 1 {
+		nights = 1
+	}
+
+	details := models.PriceDetails{Currency: Currency(html.UnescapeString(text))}
+	var stay float64
+	found := false
+	for _, part := range splitParts(text) {
+		amount, err := ParseAmount(part)
+		if err != nil {
+			continue
+		}
+		found = true
+		if details.Currency == "" {
+			details.Currency = amount.Currency
+		}
+
+		switch {
+		case totalWords.MatchString(part):
+			details.Total = amount.Value
+		case cleaningWords.MatchString(part):
+			details.CleaningFee += amount.Value
+		case serviceWords.MatchString(part):
+			details.ServiceFee += amount.Value
+		case taxWords.MatchString(part):
+			details.Taxes += amount.Value
+		case multipliedPattern.MatchString(part):
+			count, _ := strconv.Atoi(multipliedPattern.FindStringSubmatch(part)[1])
+			details.Nightly = amount.Value
+			stay = amount.Value * float64(max(count, 1))
+		case isNightly(part):
+			details.Nightly = amount.Value
+		case stay == 0:
+			stay = amount.Value
+		}
+	}
+	if !found {
+		return models.PriceDetails{}, fmt.Errorf("no amount in %q", text)
+	}
+
+	fees := details.CleaningFee + details.ServiceFee + details.Taxes
+	switch {
+	case details.Nightly == 0 && stay > 0:
+		details.Nightly = stay / float64(nights)
+	case details.Nightly == 0 && details.Total > fees:
+		details.Nightly = (details.Total - fees) / float64(nights)
+	case details.Nightly == 0:
+		details.Nightly = details.Total / float64(nights)
+	}
+	if stay == 0 {
+		stay = details.Nightly * float64(nights)
+	}
+	if details.Total == 0 {
+		details.Total = stay + fees
+	}
+	return details, nil
+}
+
+// splitParts splits a price text into its lines and separated parts
+func splitParts(text string) []string {
+	return strings.FieldsFunc(html.UnescapeString(text), func(r rune) bool {
+		return r == '\n' || r == '·' || r == '|' || r == '•'
+	})
+}
+
+// isNightly reports whether a part is a nightly rate rather than a price "for 3 nights" or
+// "2 nights €310"
+func isNightly(part string) bool {
+	if !nightWords.MatchString(part) {
+		return false
+	}
+	if perNightWords.MatchString(part) {
+		return true
+	}
+	return !forWords.MatchString(part) && !stayLengthPattern.MatchString(part)
+}
+
+// keywordPattern matches any of the words, case-insensitively and not as part of a longer word
+func keywordPattern(words ...string) *regexp.Regexp {
+	quoted := make([]string, len(words))
+	for i, word := range words {
+		quoted[i] = regexp.QuoteMeta(word)
+	}
+	return regexp.MustCompile(`(?i)(?:^|[^\p{L}\p{N}])(?:` + strings.Join(quoted, "|") + `)(?:[^\p{L}\p{N}]|$)`)
+}
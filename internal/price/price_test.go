@@ -0,0 +1,111 @@
+package price
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zenha/oliveiras/internal/models"
+)
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		text   string
+		amount Amount
+		err    string
+	}{
+		{text: "€1.234,56", amount: Amount{Value: 1234.56, Currency: "EUR"}},
+		{text: "1,234 €", amount: Amount{Value: 1234, Currency: "EUR"}},
+		{text: "€120 x 3 nights", amount: Amount{Value: 120, Currency: "EUR"}},
+		{text: "2 nights €310", amount: Amount{Value: 310, Currency: "EUR"}},
+		{text: "3 x US$ 99.50", amount: Amount{Value: 99.5, Currency: "USD"}},
+		{text: "Price for 2 nights, 4 guests: 1 480 EUR", amount: Amount{Value: 1480, Currency: "EUR"}},
+		{text: "R$&nbsp;2.500", amount: Amount{Value: 2500, Currency: "BRL"}},
+		{text: "1 234 567,50 €", amount: Amount{Value: 1234567.5, Currency: "EUR"}},
+		{text: "for 2 100 €", amount: Amount{Value: 100, Currency: "EUR"}},
+		{text: "3 1000 €", amount: Amount{Value: 1000, Currency: "EUR"}},
+		{text: "4.87 (123 reviews)", amount: Amount{Value: 4.87}},
+		{text: "Sold out", err: "no amount"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			amount, err := ParseAmount(test.text)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("expected error containing %q, got %v", test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if amount != test.amount {
+				t.Errorf("expected %+v, got %+v", test.amount, amount)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		nights  int
+		details models.PriceDetails
+	}{
+		{
+			name:    "stay price for the nights",
+			text:    "2 nights €310",
+			nights:  2,
+			details: models.PriceDetails{Nightly: 155, Total: 310, Currency: "EUR"},
+		},
+		{
+			name:    "nightly rate",
+			text:    "€125 night\n€250 total",
+			nights:  2,
+			details: models.PriceDetails{Nightly: 125, Total: 250, Currency: "EUR"},
+		},
+		{
+			name:    "nightly rate times the nights with fees",
+			text:    "€120 x 3 nights · Cleaning fee €45 · Service fee €30 · Total €435",
+			nights:  3,
+			details: models.PriceDetails{Nightly: 120, CleaningFee: 45, ServiceFee: 30, Total: 435, Currency: "EUR"},
+		},
+		{
+			name:    "total without a breakdown",
+			text:    "Total 1.234,56 €",
+			nights:  2,
+			details: models.PriceDetails{Nightly: 617.28, Total: 1234.56, Currency: "EUR"},
+		},
+		{
+			name:    "subtotal is no total",
+			text:    "Subtotal €200\nCleaning fee €40\nTotal €240",
+			nights:  2,
+			details: models.PriceDetails{Nightly: 100, CleaningFee: 40, Total: 240, Currency: "EUR"},
+		},
+		{
+			name:    "words containing tax and iva are no taxes",
+			text:    "Private villa €300\nTaxi from arrival €25 · VAT €15",
+			nights:  2,
+			details: models.PriceDetails{Nightly: 150, Taxes: 15, Total: 315, Currency: "EUR"},
+		},
+		{
+			name:    "stay price after a guest count",
+			text:    "for 2 100 €",
+			nights:  2,
+			details: models.PriceDetails{Nightly: 50, Total: 100, Currency: "EUR"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			details, err := Parse(test.text, test.nights)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if details != test.details {
+				t.Errorf("expected %+v, got %+v", test.details, details)
+			}
+		})
+	}
+}
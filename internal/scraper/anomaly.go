@@ -0,0 +1,134 @@
+package scraper
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/zenha/oliveiras/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Anomaly thresholds. Counts and prices are compared with the median of the recent scrapes
+// of the same query that were not suspicious themselves.
+const (
+	// anomalyHistory is how many recent scrapes a new one is compared with
+	anomalyHistory = 5
+	// minCountShare is the share of the usual listing count below which a scrape is suspicious
+	minCountShare = 0.3
+	// minUsualCount is the usual count from which a low count is suspicious; smaller markets vary too much
+	minUsualCount = 5
+	// maxPriceRatio is how many times higher or lower than usual the median price may be
+	maxPriceRatio = 3.0
+	// maxRejectedShare is the share of rejected listings above which a scrape is suspicious
+	maxRejectedShare = 0.5
+	// minRejectedSample is the number of listings from which the rejected share is checked
+	minRejectedSample = 4
+	// maxPrice is the highest believable listing price
+	maxPrice = 100000
+)
+
+// AnomalyError reports scraper output that looks wrong, typically because a parser broke.
+// Its listings are not stored.
+type AnomalyError struct {
+	Anomalies []string
+}
+
+func (e *AnomalyError) Error() string {
+	return "suspicious output: " + strings.Join(e.Anomalies, "; ")
+}
+
+// AnomalyAlert describes a suspicious platform scrape for whoever watches the scrapers
+type AnomalyAlert struct {
+	Platform  string
+	Query     models.SearchQuery
+	RunID     primitive.ObjectID
+	Anomalies []string
+}
+
+// SetAnomalyHandler makes the service call alert for every suspicious platform scrape
+func (s *Service) SetAnomalyHandler(alert func(AnomalyAlert)) {
+	s.onAnomaly = alert
+}
+
+// detectAnomalies checks scraped listings against the recent scrapes of the same query
+func (s *Service) detectAnomalies(platform string, query models.SearchQuery, listings []models.ScrapedListing, rejected int) []string {
+	history, err := s.store.GetRecentScrapeRecords(platform, query.Key(), anomalyHistory)
+	if err != nil {
+		log.Printf("Failed to read recent %s scrapes: %v\n", platform, err)
+	}
+	return DetectAnomalies(query, listings, rejected, history)
+}
+
+// DetectAnomalies returns what looks wrong in a scrape: many rejected listings, absurd prices,
+// or, compared with the history of the same query, far fewer listings or a median price far
+// from the usual one. Without history only the first two are checked.
+func DetectAnomalies(query models.SearchQuery, listings []models.ScrapedListing, rejected int, history []models.ScrapeRecord) []string {
+	anomalies := []string{}
+
+	total := len(listings) + rejected
+	if total >= minRejectedSample && float64(rejected) > maxRejectedShare*float64(total) {
+		anomalies = append(anomalies, fmt.Sprintf("%d of %d listings rejected", rejected, total))
+	}
+
+	prices := []float64{}
+	absurd := 0
+	for _, listing := range listings {
+		if listing.Price > maxPrice {
+			absurd++
+		}
+		if listing.StartDate == query.StartDate && listing.EndDate == query.EndDate {
+			prices = append(prices, listing.Price)
+		}
+	}
+	if absurd > 0 {
+		anomalies = append(anomalies, fmt.Sprintf("%d listings priced above %d", absurd, maxPrice))
+	}
+
+	counts := []float64{}
+	medians := []float64{}
+	for _, record := range history {
+		counts = append(counts, float64(record.Listings))
+		if len(record.Snapshot) > 0 {
+			snapshot := make([]float64, 0, len(record.Snapshot))
+			for _, listing := range record.Snapshot {
+				snapshot = append(snapshot, listing.Price)
+			}
+			medians = append(medians, median(snapshot))
+		}
+	}
+
+	if len(counts) > 0 {
+		usual := median(counts)
+		switch {
+		case len(prices) == 0 && usual > 0:
+			anomalies = append(anomalies, fmt.Sprintf("no listings, usually %.0f", usual))
+		case usual >= minUsualCount && float64(len(prices)) < minCountShare*usual:
+			anomalies = append(anomalies, fmt.Sprintf("only %d listings, usually %.0f", len(prices), usual))
+		}
+	}
+
+	if len(medians) > 0 && len(prices) > 0 {
+		usual := median(medians)
+		current := median(prices)
+		if usual > 0 && (current > usual*maxPriceRatio || current < usual/maxPriceRatio) {
+			anomalies = append(anomalies, fmt.Sprintf("median price %.2f, usually %.2f", current, usual))
+		}
+	}
+	return anomalies
+}
+
+// median returns the middle value of the values, or 0 when there are none
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
@@ -0,0 +1,70 @@
+package scraper
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Archive keeps the raw output of every scrape gzip-compressed on disk, so listings can be
+// derived again when decoding or normalization changes
+type Archive struct {
+	dir string
+}
+
+// NewArchive creates an archive storing payloads under dir
+func NewArchive(dir string) *Archive {
+	return &Archive{dir: dir}
+}
+
+// Save compresses a payload into <dir>/<platform>/<id>.jsonl.gz and returns its path
+// relative to the archive directory
+func (a *Archive) Save(platform, id string, payload []byte) (string, error) {
+	name := filepath.Join(platform, id+".jsonl.gz")
+	path := filepath.Join(a.dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", fmt.Errorf("creating archive directory: %v", err)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	writer := gzip.NewWriter(file)
+	if _, err := writer.Write(payload); err != nil {
+		return "", err
+	}
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+	return name, file.Close()
+}
+
+// Open returns a reader of the decompressed payload stored under name
+func (a *Archive) Open(name string) (io.ReadCloser, error) {
+	file, err := os.Open(filepath.Join(a.dir, name))
+	if err != nil {
+		return nil, err
+	}
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &archiveReader{Reader: reader, file: file}, nil
+}
+
+// archiveReader closes both the decompressor and the file
+type archiveReader struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func (r *archiveReader) Close() error {
+	r.Reader.Close()
+	return r.file.Close()
+}
@@ -0,0 +1,99 @@
+package scraper
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/zenha/oliveiras/internal/models"
+)
+
+// ListingDiff describes how the listings of a query changed since its previous scrape
+type ListingDiff struct {
+	// Since is when the previous scrape started
+	Since time.Time
+	// New and Gone hold the URLs that appeared and disappeared
+	New  []string
+	Gone []string
+	// PriceDrops and PriceRises hold the listings whose price changed
+	PriceDrops []PriceChange
+	PriceRises []PriceChange
+	// Unchanged counts the listings found both times at the same price
+	Unchanged int
+}
+
+// PriceChange is the price of one listing in two consecutive scrapes
+type PriceChange struct {
+	URL    string
+	Before float64
+	After  float64
+}
+
+// Percent returns the relative change, e.g. -8 for a price 8% lower
+func (c PriceChange) Percent() float64 {
+	if c.Before == 0 {
+		return 0
+	}
+	return (c.After - c.Before) / c.Before * 100
+}
+
+// AveragePercent returns the mean relative change of the price changes
+func AveragePercent(changes []PriceChange) float64 {
+	if len(changes) == 0 {
+		return 0
+	}
+	total := 0.0
+	for _, change := range changes {
+		total += change.Percent()
+	}
+	return total / float64(len(changes))
+}
+
+// priceTolerance ignores differences from rounding
+const priceTolerance = 0.005
+
+// Snapshot returns the URL and price of every listing, sorted by URL
+func Snapshot(listings []models.ScrapedListing) []models.ListingPrice {
+	snapshot := make([]models.ListingPrice, 0, len(listings))
+	for _, listing := range listings {
+		snapshot = append(snapshot, models.ListingPrice{URL: listing.URL, Price: listing.Price})
+	}
+	sort.Slice(snapshot, func(i, j int) bool { return snapshot[i].URL < snapshot[j].URL })
+	return snapshot
+}
+
+// CompareSnapshots matches two snapshots of the same query by URL
+func CompareSnapshots(previous, current []models.ListingPrice) ListingDiff {
+	before := make(map[string]float64, len(previous))
+	for _, listing := range previous {
+		before[listing.URL] = listing.Price
+	}
+
+	diff := ListingDiff{}
+	seen := make(map[string]bool, len(current))
+	for _, listing := range current {
+		seen[listing.URL] = true
+		price, ok := before[listing.URL]
+		switch {
+		case !ok:
+			diff.New = append(diff.New, listing.URL)
+		case math.Abs(listing.Price-price) < priceTolerance:
+			diff.Unchanged++
+		case listing.Price < price:
+			diff.PriceDrops = append(diff.PriceDrops, PriceChange{URL: listing.URL, Before: price, After: listing.Price})
+		default:
+			diff.PriceRises = append(diff.PriceRises, PriceChange{URL: listing.URL, Before: price, After: listing.Price})
+		}
+	}
+	for _, listing := range previous {
+		if !seen[listing.URL] {
+			diff.Gone = append(diff.Gone, listing.URL)
+		}
+	}
+	return diff
+}
+
+// Changed reports whether anything differs from the previous scrape
+func (d *ListingDiff) Changed() bool {
+	return len(d.New) > 0 || len(d.Gone) > 0 || len(d.PriceDrops) > 0 || len(d.PriceRises) > 0
+}
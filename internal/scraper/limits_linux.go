@@ -0,0 +1,267 @@
+//go:build linux
+
+package scraper
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/zenha/oliveiras/internal/models"
+	"golang.org/x/sys/unix"
+)
+
+// clockTicks is the unit of the CPU times in /proc, fixed at 100 per second on Linux
+const clockTicks = 100
+
+// wrapLimits rewrites the command to start through the bot's own binary, which sets the
+// limits and then replaces itself with the script, so they hold from the script's first
+// instruction. With perRequestCPU the CPU limit is only a soft one, renewed for every request
+// with renewCPULimit, so a long-lived worker is not killed for the CPU time of earlier requests.
+func wrapLimits(cmd *exec.Cmd, limits Limits, perRequestCPU bool) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("finding the sandbox wrapper: %v", err)
+	}
+
+	args := []string{executable, SandboxExecCommand,
+		"-data", strconv.FormatUint(limits.MemoryBytes, 10),
+		"-cpu", strconv.FormatUint(limits.CPUSeconds, 10),
+		"-nofile", strconv.FormatUint(limits.OpenFiles, 10),
+	}
+	if perRequestCPU {
+		args = append(args, "-soft-cpu")
+	}
+	if limits.Processes > 0 {
+		// RLIMIT_NPROC counts every task of the user, including the bot's own threads, so the
+		// scraper gets its allowance on top of what the user already runs
+		tasks, err := userTasks()
+		if err != nil {
+			return fmt.Errorf("counting the user's processes: %v", err)
+		}
+		args = append(args, "-nproc", strconv.FormatUint(tasks+limits.Processes, 10))
+	}
+	cmd.Args = append(append(args, "--", cmd.Path), cmd.Args[1:]...)
+	cmd.Path = executable
+	return nil
+}
+
+// RunSandboxExec is the sandbox-exec subcommand: it sets the resource limits given as flags
+// and replaces itself with the command that follows them. Limits of zero are left unset.
+func RunSandboxExec(args []string) error {
+	flags := flag.NewFlagSet(SandboxExecCommand, flag.ContinueOnError)
+	data := flags.Uint64("data", 0, "data segment size in bytes")
+	cpu := flags.Uint64("cpu", 0, "CPU time in seconds")
+	softCPU := flags.Bool("soft-cpu", false, "only set the soft CPU limit")
+	nofile := flags.Uint64("nofile", 0, "open files")
+	nproc := flags.Uint64("nproc", 0, "tasks of the user")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	command := flags.Args()
+	if len(command) == 0 {
+		return errors.New("usage: sandbox-exec [limits] -- command [args...]")
+	}
+
+	cpuMax := *cpu
+	if *softCPU {
+		cpuMax = unix.RLIM_INFINITY
+	}
+	// The process limit comes last, as the Go runtime may still start threads before exec. The
+	// memory limit is RLIMIT_DATA rather than RLIMIT_AS, as browsers reserve far more address
+	// space than they use.
+	for _, limit := range []struct {
+		resource int
+		cur, max uint64
+		name     string
+	}{
+		{unix.RLIMIT_DATA, *data, *data, "memory"},
+		{unix.RLIMIT_CPU, *cpu, cpuMax, "CPU time"},
+		{unix.RLIMIT_NOFILE, *nofile, *nofile, "open files"},
+		{unix.RLIMIT_NPROC, *nproc, *nproc, "processes"},
+	} {
+		if limit.cur == 0 {
+			continue
+		}
+		if err := syscall.Setrlimit(limit.resource, &syscall.Rlimit{Cur: limit.cur, Max: limit.max}); err != nil {
+			return fmt.Errorf("limiting scraper %s: %v", limit.name, err)
+		}
+	}
+
+	return syscall.Exec(command[0], command, os.Environ())
+}
+
+// renewCPULimit gives every process in the group a CPU time allowance of seconds on top of
+// what it used so far. Processes started during the request inherit their parent's limit.
+func renewCPULimit(pgid int, seconds uint64) error {
+	processes, err := groupProcesses(pgid)
+	if err != nil {
+		return err
+	}
+	for _, process := range processes {
+		var current unix.Rlimit
+		if err := unix.Prlimit(process.pid, unix.RLIMIT_CPU, nil, &current); err != nil {
+			// The process exited since the group was listed
+			continue
+		}
+		soft := (process.utime+process.stime)/clockTicks + 1 + seconds
+		if current.Max != unix.RLIM_INFINITY && soft > current.Max {
+			soft = current.Max
+		}
+		if err := unix.Prlimit(process.pid, unix.RLIMIT_CPU, &unix.Rlimit{Cur: soft, Max: current.Max}, nil); err != nil && !errors.Is(err, unix.ESRCH) {
+			return fmt.Errorf("renewing CPU limit of pid %d: %v", process.pid, err)
+		}
+	}
+	return nil
+}
+
+// groupUsage adds up the CPU time the processes of a group and the children they reaped have
+// used so far, with the largest peak resident set among them
+func groupUsage(pgid int) (models.ResourceUsage, bool) {
+	processes, err := groupProcesses(pgid)
+	if err != nil || len(processes) == 0 {
+		return models.ResourceUsage{}, false
+	}
+	var usage models.ResourceUsage
+	var user, system uint64
+	for _, process := range processes {
+		user += process.utime + process.cutime
+		system += process.stime + process.cstime
+		usage.MaxRSSBytes = max(usage.MaxRSSBytes, process.peakRSS)
+	}
+	usage.User = ticksToDuration(user)
+	usage.System = ticksToDuration(system)
+	return usage, true
+}
+
+// resourceUsage reads the CPU time and peak memory of a finished process
+func resourceUsage(state *os.ProcessState) (models.ResourceUsage, bool) {
+	rusage, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok {
+		return models.ResourceUsage{}, false
+	}
+	return models.ResourceUsage{
+		User:   time.Duration(rusage.Utime.Nano()),
+		System: time.Duration(rusage.Stime.Nano()),
+		// Linux reports the peak resident set in kilobytes
+		MaxRSSBytes: rusage.Maxrss * 1024,
+	}, true
+}
+
+// procProcess is what /proc reports about one process
+type procProcess struct {
+	pid                          int
+	utime, stime, cutime, cstime uint64
+	peakRSS                      int64
+}
+
+// groupProcesses lists the processes of a process group from /proc
+func groupProcesses(pgid int) ([]procProcess, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+	processes := []procProcess{}
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		stat, err := os.ReadFile(filepath.Join("/proc", entry.Name(), "stat"))
+		if err != nil {
+			continue
+		}
+		// The command name may contain spaces; the fields after it are space separated
+		end := strings.LastIndexByte(string(stat), ')')
+		if end < 0 {
+			continue
+		}
+		fields := strings.Fields(string(stat[end+1:]))
+		if len(fields) < 15 || fields[2] != strconv.Itoa(pgid) {
+			continue
+		}
+		process := procProcess{pid: pid}
+		for i, value := range []*uint64{&process.utime, &process.stime, &process.cutime, &process.cstime} {
+			*value, _ = strconv.ParseUint(fields[11+i], 10, 64)
+		}
+		process.peakRSS, _ = statusValue(pid, "VmHWM")
+		process.peakRSS *= 1024
+		processes = append(processes, process)
+	}
+	return processes, nil
+}
+
+// userTasks counts the tasks of every process whose real user is the bot's, which is what
+// RLIMIT_NPROC is checked against
+func userTasks() (uint64, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return 0, err
+	}
+	uid := strconv.Itoa(os.Getuid())
+	var tasks uint64
+	for _, entry := range entries {
+		if _, err := strconv.Atoi(entry.Name()); err != nil {
+			continue
+		}
+		status, err := os.Open(filepath.Join("/proc", entry.Name(), "status"))
+		if err != nil {
+			continue
+		}
+		owned, threads := false, uint64(1)
+		scanner := bufio.NewScanner(status)
+		for scanner.Scan() {
+			key, value, _ := strings.Cut(scanner.Text(), ":")
+			fields := strings.Fields(value)
+			if len(fields) == 0 {
+				continue
+			}
+			switch key {
+			case "Uid":
+				owned = fields[0] == uid
+			case "Threads":
+				if count, err := strconv.ParseUint(fields[0], 10, 64); err == nil && count > 0 {
+					threads = count
+				}
+			}
+		}
+		status.Close()
+		if owned {
+			tasks += threads
+		}
+	}
+	return tasks, nil
+}
+
+// statusValue reads a numeric field of /proc/<pid>/status
+func statusValue(pid int, key string) (int64, error) {
+	status, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "status"))
+	if err != nil {
+		return 0, err
+	}
+	for _, line := range strings.Split(string(status), "\n") {
+		name, value, ok := strings.Cut(line, ":")
+		if !ok || name != key {
+			continue
+		}
+		fields := strings.Fields(value)
+		if len(fields) == 0 {
+			break
+		}
+		return strconv.ParseInt(fields[0], 10, 64)
+	}
+	return 0, fmt.Errorf("no %s in status of pid %d", key, pid)
+}
+
+// ticksToDuration converts a CPU time in clock ticks
+func ticksToDuration(ticks uint64) time.Duration {
+	return time.Duration(ticks) * time.Second / clockTicks
+}
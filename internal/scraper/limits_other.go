@@ -0,0 +1,43 @@
+//go:build !linux
+
+package scraper
+
+import (
+	"errors"
+	"log"
+	"os"
+	"os/exec"
+	"sync"
+
+	"github.com/zenha/oliveiras/internal/models"
+)
+
+var warnLimits sync.Once
+
+// wrapLimits is unsupported outside Linux; scraper processes run without limits
+func wrapLimits(cmd *exec.Cmd, limits Limits, perRequestCPU bool) error {
+	warnLimits.Do(func() {
+		log.Println("Scraper resource limits are only supported on Linux")
+	})
+	return nil
+}
+
+// RunSandboxExec is unsupported outside Linux
+func RunSandboxExec(args []string) error {
+	return errors.New("sandbox-exec is only supported on Linux")
+}
+
+// renewCPULimit is unsupported outside Linux
+func renewCPULimit(pgid int, seconds uint64) error {
+	return nil
+}
+
+// groupUsage is unsupported outside Linux
+func groupUsage(pgid int) (models.ResourceUsage, bool) {
+	return models.ResourceUsage{}, false
+}
+
+// resourceUsage is unsupported outside Linux
+func resourceUsage(state *os.ProcessState) (models.ResourceUsage, bool) {
+	return models.ResourceUsage{}, false
+}
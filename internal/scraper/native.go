@@ -0,0 +1,129 @@
+package scraper
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/zenha/oliveiras/internal/models"
+	"github.com/zenha/oliveiras/internal/parser"
+)
+
+// maxPageSize bounds a fetched search results page
+const maxPageSize = 20 * 1024 * 1024
+
+// nativeUserAgent is sent with page requests, as platforms turn away clients that name none
+const nativeUserAgent = "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0 Safari/537.36"
+
+// searchURLs build the search results page URL of a query on each platform with a native parser
+var searchURLs = map[string]func(base string, query models.SearchQuery) string{
+	PlatformAirbnb:  airbnbSearchURL,
+	PlatformBooking: bookingSearchURL,
+}
+
+// defaultBaseURLs are the sites native providers fetch from
+var defaultBaseURLs = map[string]string{
+	PlatformAirbnb:  "https://www.airbnb.com",
+	PlatformBooking: "https://www.booking.com",
+}
+
+// nativeProvider scrapes a platform without the script, fetching its search results page and
+// parsing it with the Go parser
+type nativeProvider struct {
+	platform string
+	baseURL  string
+	client   *http.Client
+}
+
+// NewNativeProvider returns a provider that fetches the platform's search results page with
+// client and parses it in Go. Only platforms with a parser are supported.
+func NewNativeProvider(platform string, client *http.Client) (Provider, error) {
+	if _, ok := searchURLs[platform]; !ok {
+		return nil, fmt.Errorf("no native scraper for platform %q", platform)
+	}
+	return &nativeProvider{platform: platform, baseURL: defaultBaseURLs[platform], client: client}, nil
+}
+
+func (p *nativeProvider) Name() string {
+	return p.platform
+}
+
+// Scrape fetches the first search results page of the query and parses its listings. Their
+// raw output is the parsed listings encoded as scraper records, so native scrapes are archived
+// and reprocessed like scripted ones.
+func (p *nativeProvider) Scrape(ctx context.Context, query models.SearchQuery) (*Output, error) {
+	if query.Location == "" {
+		return nil, errors.New("native scraping needs a location")
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, searchURLs[p.platform](p.baseURL, query), nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("User-Agent", nativeUserAgent)
+	request.Header.Set("Accept-Language", "en-GB,en;q=0.9")
+
+	response, err := p.client.Do(request)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, fmt.Errorf("fetching %s search page: %v", p.platform, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s search page: %s", p.platform, response.Status)
+	}
+
+	listings, err := parser.Listings(p.platform, io.LimitReader(response.Body, maxPageSize), query.StartDate, query.EndDate)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw bytes.Buffer
+	if err := EncodeListings(&raw, p.platform, listings); err != nil {
+		return nil, err
+	}
+	return &Output{Listings: listings, Raw: raw.Bytes()}, nil
+}
+
+// airbnbSearchURL searches homes in the query's location for its stay and guests, with its
+// price and bedroom filters
+func airbnbSearchURL(base string, query models.SearchQuery) string {
+	params := url.Values{}
+	params.Set("checkin", query.StartDate)
+	params.Set("checkout", query.EndDate)
+	if query.Guests > 0 {
+		params.Set("adults", strconv.Itoa(query.Guests))
+	}
+	if query.MinPrice > 0 {
+		params.Set("price_min", strconv.FormatFloat(query.MinPrice, 'f', 0, 64))
+	}
+	if query.MaxPrice > 0 {
+		params.Set("price_max", strconv.FormatFloat(query.MaxPrice, 'f', 0, 64))
+	}
+	if query.MinBedrooms > 0 {
+		params.Set("min_bedrooms", strconv.Itoa(query.MinBedrooms))
+	}
+	return strings.TrimSuffix(base, "/") + "/s/" + url.PathEscape(query.Location) + "/homes?" + params.Encode()
+}
+
+// bookingSearchURL searches properties in the query's location for its stay and guests in one
+// room. Booking's price and bedroom filters have no stable parameters, so they are not applied.
+func bookingSearchURL(base string, query models.SearchQuery) string {
+	params := url.Values{}
+	params.Set("ss", query.Location)
+	params.Set("checkin", query.StartDate)
+	params.Set("checkout", query.EndDate)
+	if query.Guests > 0 {
+		params.Set("group_adults", strconv.Itoa(query.Guests))
+	}
+	params.Set("no_rooms", "1")
+	return strings.TrimSuffix(base, "/") + "/searchresults.en-gb.html?" + params.Encode()
+}
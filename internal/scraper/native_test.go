@@ -0,0 +1,128 @@
+package scraper
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/zenha/oliveiras/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var nativeQuery = models.SearchQuery{
+	StartDate: "2025-01-14",
+	EndDate:   "2025-01-16",
+	Market:    models.Market{Location: "Ericeira, Portugal", Guests: 4, MinBedrooms: 2},
+}
+
+// servePage serves a saved search results page at path, failing the test on other requests
+func servePage(t *testing.T, path, page string, check func(*http.Request)) *httptest.Server {
+	t.Helper()
+	content, err := os.ReadFile(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path {
+			t.Errorf("unexpected request for %s", r.URL.Path)
+			http.NotFound(w, r)
+			return
+		}
+		check(r)
+		w.Write(content)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestNativeScrapeStoresParsedListings(t *testing.T) {
+	server := servePage(t, "/s/Ericeira, Portugal/homes", "../parser/fixtures/airbnb_search.html", func(r *http.Request) {
+		params := r.URL.Query()
+		if params.Get("checkin") != "2025-01-14" || params.Get("checkout") != "2025-01-16" || params.Get("adults") != "4" || params.Get("min_bedrooms") != "2" {
+			t.Errorf("unexpected search parameters %s", r.URL.RawQuery)
+		}
+	})
+	provider := &nativeProvider{platform: PlatformAirbnb, baseURL: server.URL, client: server.Client()}
+
+	store := newMemoryStore()
+	archive := NewArchive(t.TempDir())
+	service := NewService(NewRegistry(provider), store, RetryPolicy{Attempts: 1})
+	service.SetArchive(archive)
+
+	runID := primitive.NewObjectID()
+	result := service.ScrapePlatform(context.Background(), runID, nativeQuery, PlatformAirbnb, false)
+	if result.Err != nil {
+		t.Fatal(result.Err)
+	}
+	if len(result.Listings) != 4 || len(store.listings) != 4 {
+		t.Fatalf("expected 4 listings analyzed and stored, got %d and %d", len(result.Listings), len(store.listings))
+	}
+	if listing := result.Listings[0]; listing.URL != "https://www.airbnb.com/rooms/12345678" || listing.Price != 125 || listing.Reviews != 85 {
+		t.Errorf("unexpected first listing %+v", listing)
+	}
+
+	if len(store.records) != 1 {
+		t.Fatalf("expected one scrape record, got %d", len(store.records))
+	}
+	for _, record := range store.records {
+		if record.Listings != 4 || record.Archive == "" {
+			t.Fatalf("expected an archived scrape of 4 listings, got %+v", record)
+		}
+		// The archived output decodes like the script's
+		counted, rejected, err := service.Reprocess(record)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if counted != 4 || rejected != 0 {
+			t.Errorf("expected to reprocess 4 listings, got %d and %d rejected", counted, rejected)
+		}
+	}
+}
+
+func TestNativeScrapeBooking(t *testing.T) {
+	server := servePage(t, "/searchresults.en-gb.html", "../parser/fixtures/booking_search.html", func(r *http.Request) {
+		params := r.URL.Query()
+		if params.Get("ss") != "Ericeira, Portugal" || params.Get("checkin") != "2025-01-14" || params.Get("group_adults") != "4" {
+			t.Errorf("unexpected search parameters %s", r.URL.RawQuery)
+		}
+	})
+	provider := &nativeProvider{platform: PlatformBooking, baseURL: server.URL, client: server.Client()}
+
+	output, err := provider.Scrape(context.Background(), nativeQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+	listings, rejected := normalizeListings(PlatformBooking, output.Listings, nativeQuery)
+	if len(listings) != 3 || rejected != 0 {
+		t.Fatalf("expected 3 valid listings, got %d and %d rejected", len(listings), rejected)
+	}
+	if listing := listings[0]; listing.Name != "Casa do Mar" || listing.Price != 123 || listing.TotalPrice != 254 || listing.Rating != 8.7 {
+		t.Errorf("unexpected first listing %+v", listing)
+	}
+
+	archived, rejected, err := DecodeListings(strings.NewReader(string(output.Raw)), PlatformBooking, nativeQuery)
+	if err != nil || len(archived) != 3 || rejected != 0 {
+		t.Errorf("expected the raw output to decode to the 3 listings, got %d, %d rejected, %v", len(archived), rejected, err)
+	}
+}
+
+func TestNativeScrapeFailures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "blocked", http.StatusForbidden)
+	}))
+	defer server.Close()
+	provider := &nativeProvider{platform: PlatformAirbnb, baseURL: server.URL, client: server.Client()}
+
+	if _, err := provider.Scrape(context.Background(), models.SearchQuery{StartDate: "2025-01-14", EndDate: "2025-01-16"}); err == nil || !strings.Contains(err.Error(), "needs a location") {
+		t.Errorf("expected a missing location error, got %v", err)
+	}
+	if _, err := provider.Scrape(context.Background(), nativeQuery); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("expected the HTTP status as error, got %v", err)
+	}
+	if _, err := NewNativeProvider("expedia", http.DefaultClient); err == nil {
+		t.Error("expected an error for a platform without a parser")
+	}
+}
@@ -0,0 +1,104 @@
+package scraper
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/zenha/oliveiras/internal/models"
+	"github.com/zenha/oliveiras/internal/price"
+)
+
+// NormalizeListing validates a scraped listing and cleans it for storage. Missing stay dates
+// are taken from the query, URLs lose their search parameters so the same listing keeps
+// the same URL across searches, and a listing reporting only its stay price gets the
+// nightly price derived from it.
+func NormalizeListing(listing models.ScrapedListing, query models.SearchQuery) (models.ScrapedListing, error) {
+	listing.Name = strings.TrimSpace(listing.Name)
+	listing.BedConfiguration = strings.TrimSpace(listing.BedConfiguration)
+
+	link, err := canonicalURL(listing.URL)
+	if err != nil {
+		return listing, err
+	}
+	listing.URL = link
+
+	if listing.StartDate == "" {
+		listing.StartDate = query.StartDate
+	}
+	if listing.EndDate == "" {
+		listing.EndDate = query.EndDate
+	}
+	start, err := time.Parse("2006-01-02", listing.StartDate)
+	if err != nil {
+		return listing, fmt.Errorf("invalid start date %q", listing.StartDate)
+	}
+	end, err := time.Parse("2006-01-02", listing.EndDate)
+	if err != nil {
+		return listing, fmt.Errorf("invalid end date %q", listing.EndDate)
+	}
+	if !end.After(start) {
+		return listing, fmt.Errorf("end date %s is not after start date %s", listing.EndDate, listing.StartDate)
+	}
+	nights := int(end.Sub(start).Hours() / 24)
+
+	if listing.TotalPrice < 0 || math.IsNaN(listing.TotalPrice) || math.IsInf(listing.TotalPrice, 0) {
+		listing.TotalPrice = 0
+	}
+	if listing.Price == 0 && listing.TotalPrice > 0 {
+		listing.Price = listing.TotalPrice / float64(nights)
+	}
+	if listing.Price <= 0 || math.IsNaN(listing.Price) || math.IsInf(listing.Price, 0) {
+		return listing, fmt.Errorf("invalid price %v", listing.Price)
+	}
+	if listing.Rating < 0 || listing.Rating > 10 || math.IsNaN(listing.Rating) {
+		listing.Rating = 0
+	}
+	if listing.Reviews < 0 {
+		listing.Reviews = 0
+	}
+
+	// The breakdown is extra information; a price text that cannot be read does not reject
+	// a listing whose price is valid
+	if listing.PriceText != "" {
+		if details, err := price.Parse(listing.PriceText, nights); err == nil {
+			listing.PriceDetails = &details
+		}
+		listing.PriceText = ""
+	}
+	if listing.PriceDetails != nil && !validPriceDetails(*listing.PriceDetails) {
+		listing.PriceDetails = nil
+	}
+	return listing, nil
+}
+
+// validPriceDetails checks that every amount of a price breakdown is finite and not negative
+func validPriceDetails(details models.PriceDetails) bool {
+	for _, amount := range []float64{details.Nightly, details.Total, details.CleaningFee, details.ServiceFee, details.Taxes} {
+		if amount < 0 || math.IsNaN(amount) || math.IsInf(amount, 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// canonicalURL checks that a listing URL is absolute and strips its query and fragment
+func canonicalURL(link string) (string, error) {
+	link = strings.TrimSpace(link)
+	if link == "" {
+		return "", errors.New("missing url")
+	}
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return "", fmt.Errorf("invalid url %q", link)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" || parsed.Host == "" {
+		return "", fmt.Errorf("invalid url %q", link)
+	}
+	parsed.RawQuery = ""
+	parsed.Fragment = ""
+	return parsed.String(), nil
+}
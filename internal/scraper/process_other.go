@@ -0,0 +1,14 @@
+//go:build !unix
+
+package scraper
+
+import (
+	"os/exec"
+	"time"
+)
+
+// waitDelay bounds how long Wait keeps reading output after the process is killed
+const waitDelay = 5 * time.Second
+
+// killProcessGroup keeps the default cancellation, which only kills the script itself
+func killProcessGroup(cmd *exec.Cmd) {}
@@ -0,0 +1,21 @@
+//go:build unix
+
+package scraper
+
+import (
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// waitDelay bounds how long Wait keeps reading output after the process is killed
+const waitDelay = 5 * time.Second
+
+// killProcessGroup starts the command in its own process group and makes cancellation
+// kill the whole group, so the headless browsers started by the script do not outlive it.
+func killProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
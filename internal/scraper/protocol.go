@@ -0,0 +1,290 @@
+package scraper
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/zenha/oliveiras/internal/models"
+)
+
+// The scraper script writes one JSON record per line to stdout and its logs to stderr.
+// Every record is an envelope carrying the protocol version, the record type, the
+// platform it refers to and a type-specific payload:
+//
+//	{"v":1,"type":"progress","platform":"airbnb","data":{"message":"page 2","done":2,"total":5}}
+//	{"v":1,"type":"listing","platform":"airbnb","data":{"url":"...","start_date":"2025-01-14",...}}
+//	{"v":1,"type":"summary","platform":"airbnb","data":{"average_price":120.5,...}}
+//	{"v":1,"type":"error","platform":"booking","data":{"message":"blocked by captcha"}}
+//
+// Each platform must end with either a summary or an error record.
+//
+// A long-lived worker (see WorkerPool) answers requests with the same records, tagged with
+// the id of the request they answer, and replies to pings with a pong record:
+//
+//	{"v":1,"id":7,"type":"pong"}
+
+// ProtocolVersion is the version of the scraper output protocol understood by this decoder
+const ProtocolVersion = 1
+
+// Record types of the scraper output protocol
+const (
+	RecordProgress = "progress"
+	RecordListing  = "listing"
+	RecordSummary  = "summary"
+	RecordError    = "error"
+	RecordPong     = "pong"
+)
+
+// Platforms reported by the scraper
+const (
+	PlatformAirbnb  = "airbnb"
+	PlatformBooking = "booking"
+	PlatformVrbo    = "vrbo"
+)
+
+// maxRecordSize bounds a single output line
+const maxRecordSize = 1024 * 1024
+
+// Record is one line of scraper output
+type Record struct {
+	Version int `json:"v"`
+	// ID is the worker request the record answers; one-shot runs leave it empty
+	ID       uint64          `json:"id,omitempty"`
+	Type     string          `json:"type"`
+	Platform string          `json:"platform"`
+	Data     json.RawMessage `json:"data"`
+
+	// line is the output line the record was decoded from
+	line []byte
+}
+
+// ProgressRecord reports how far the scraper got on a platform
+type ProgressRecord struct {
+	Message string `json:"message"`
+	Done    int    `json:"done"`
+	Total   int    `json:"total"`
+}
+
+// ErrorRecord reports a platform failure
+type ErrorRecord struct {
+	Message string `json:"message"`
+}
+
+// PlatformResult collects the decoded records of one platform
+type PlatformResult struct {
+	Listings []models.ScrapedListing
+	Summary  *models.ListingAnalysis
+	Error    string
+	// Skipped counts the platform's records that could not be decoded
+	Skipped int
+}
+
+// Result collects the decoded records of a scraper run, keyed by platform
+type Result struct {
+	Platforms map[string]*PlatformResult
+	// Skipped counts the lines that were not valid records of any platform
+	Skipped int
+}
+
+// Platform returns the result for a platform, or an error if the platform failed or
+// never reported a summary
+func (r *Result) Platform(platform string) (*PlatformResult, error) {
+	result, ok := r.Platforms[platform]
+	if !ok {
+		return nil, fmt.Errorf("scraper reported nothing for %s", platform)
+	}
+	if result.Error != "" {
+		return nil, fmt.Errorf("%s failed: %s", platform, result.Error)
+	}
+	if result.Summary == nil {
+		return nil, fmt.Errorf("scraper reported no summary for %s", platform)
+	}
+	return result, nil
+}
+
+// ProtocolError describes invalid scraper output
+type ProtocolError struct {
+	Line int
+	Err  error
+}
+
+func (e *ProtocolError) Error() string {
+	return fmt.Sprintf("scraper output line %d: %v", e.Line, e.Err)
+}
+
+func (e *ProtocolError) Unwrap() error {
+	return e.Err
+}
+
+// Decoder reads and validates scraper output records
+type Decoder struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+// NewDecoder creates a decoder reading records from r
+func NewDecoder(r io.Reader) *Decoder {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxRecordSize)
+	return &Decoder{scanner: scanner}
+}
+
+// Next returns the next valid record, skipping blank lines. It returns io.EOF at the end
+// of the output and a *ProtocolError for a malformed record, after which decoding can
+// continue with the next line.
+func (d *Decoder) Next() (*Record, error) {
+	for d.scanner.Scan() {
+		d.line++
+		line := d.scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var record Record
+		if err := json.Unmarshal(line, &record); err != nil {
+			return nil, &ProtocolError{Line: d.line, Err: err}
+		}
+		if err := validateRecord(&record); err != nil {
+			return nil, &ProtocolError{Line: d.line, Err: err}
+		}
+		record.line = bytes.Clone(line)
+		return &record, nil
+	}
+	if err := d.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// validateRecord checks the envelope fields of a record
+func validateRecord(record *Record) error {
+	if record.Version != ProtocolVersion {
+		return fmt.Errorf("unsupported protocol version %d, expected %d", record.Version, ProtocolVersion)
+	}
+	switch record.Type {
+	case RecordProgress, RecordListing, RecordSummary, RecordError:
+	case RecordPong:
+		return nil
+	default:
+		return fmt.Errorf("unknown record type %q", record.Type)
+	}
+	if record.Platform == "" {
+		return errors.New("missing platform")
+	}
+	if len(record.Data) == 0 {
+		return errors.New("missing data")
+	}
+	return nil
+}
+
+// Decode reads every record of a scraper run and groups them by platform. Progress
+// records are passed to onProgress when it is not nil. Malformed records are logged,
+// counted and skipped, so one bad line does not fail the whole run.
+func Decode(r io.Reader, onProgress func(platform string, progress ProgressRecord)) (*Result, error) {
+	decoder := NewDecoder(r)
+	result := &Result{Platforms: make(map[string]*PlatformResult)}
+
+	for {
+		record, err := decoder.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var protocolErr *ProtocolError
+		if errors.As(err, &protocolErr) {
+			log.Println("Skipping scraper record:", err)
+			result.Skipped++
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if record.Type == RecordPong {
+			continue
+		}
+
+		platform, ok := result.Platforms[record.Platform]
+		if !ok {
+			platform = &PlatformResult{}
+			result.Platforms[record.Platform] = platform
+		}
+		if platform.Summary != nil || platform.Error != "" {
+			log.Println("Skipping scraper record:", &ProtocolError{Line: decoder.line, Err: fmt.Errorf("%s record after %s finished", record.Type, record.Platform)})
+			platform.Skipped++
+			continue
+		}
+
+		if err := decodeRecord(record, platform, onProgress); err != nil {
+			log.Println("Skipping scraper record:", &ProtocolError{Line: decoder.line, Err: err})
+			platform.Skipped++
+		}
+	}
+
+	return result, nil
+}
+
+// decodeRecord decodes the payload of a record into the platform result
+func decodeRecord(record *Record, platform *PlatformResult, onProgress func(string, ProgressRecord)) error {
+	switch record.Type {
+	case RecordProgress:
+		var progress ProgressRecord
+		if err := json.Unmarshal(record.Data, &progress); err != nil {
+			return err
+		}
+		if onProgress != nil {
+			onProgress(record.Platform, progress)
+		}
+
+	case RecordListing:
+		var listing models.ScrapedListing
+		if err := json.Unmarshal(record.Data, &listing); err != nil {
+			return err
+		}
+		platform.Listings = append(platform.Listings, listing)
+
+	case RecordSummary:
+		var summary models.ListingAnalysis
+		if err := json.Unmarshal(record.Data, &summary); err != nil {
+			return err
+		}
+		if summary.TotalListings < 0 || summary.AveragePrice < 0 || summary.LowestPrice < 0 || summary.HighestPrice < summary.LowestPrice {
+			return fmt.Errorf("inconsistent %s summary", record.Platform)
+		}
+		platform.Summary = &summary
+
+	case RecordError:
+		var scraperErr ErrorRecord
+		if err := json.Unmarshal(record.Data, &scraperErr); err != nil {
+			return err
+		}
+		if scraperErr.Message == "" {
+			scraperErr.Message = "unknown error"
+		}
+		platform.Error = scraperErr.Message
+	}
+	return nil
+}
+
+// EncodeListings writes a platform's listings as scraper output, one listing record each
+// followed by the platform's summary, so they can be archived and decoded like the script's
+func EncodeListings(w io.Writer, platform string, listings []models.ScrapedListing) error {
+	encoder := json.NewEncoder(w)
+	write := func(recordType string, payload interface{}) error {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		return encoder.Encode(Record{Version: ProtocolVersion, Type: recordType, Platform: platform, Data: data})
+	}
+
+	for _, listing := range listings {
+		if err := write(RecordListing, listing); err != nil {
+			return err
+		}
+	}
+	return write(RecordSummary, Analyze(listings))
+}
@@ -0,0 +1,101 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/zenha/oliveiras/internal/models"
+)
+
+// Provider scrapes listings from one platform
+type Provider interface {
+	// Name is the platform name used in commands and scraper records, e.g. "airbnb"
+	Name() string
+	// Scrape returns the listings found for the query
+	Scrape(ctx context.Context, query models.SearchQuery) (*Output, error)
+}
+
+// Output is what a provider returns for a scrape
+type Output struct {
+	Listings []models.ScrapedListing
+	// Raw is the scraper output the listings were decoded from, when available
+	Raw []byte
+	// Skipped counts the malformed records left out of the listings
+	Skipped int
+	// Usage is what the scraper consumed, when it could be measured
+	Usage *models.ResourceUsage
+}
+
+// Registry holds the available providers in registration order
+type Registry struct {
+	providers map[string]Provider
+	names     []string
+}
+
+// NewRegistry creates a registry with the given providers
+func NewRegistry(providers ...Provider) *Registry {
+	r := &Registry{providers: make(map[string]Provider)}
+	for _, provider := range providers {
+		r.Register(provider)
+	}
+	return r
+}
+
+// Register adds a provider, replacing any provider with the same name
+func (r *Registry) Register(provider Provider) {
+	if _, ok := r.providers[provider.Name()]; !ok {
+		r.names = append(r.names, provider.Name())
+	}
+	r.providers[provider.Name()] = provider
+}
+
+// Names returns the registered platform names
+func (r *Registry) Names() []string {
+	return append([]string(nil), r.names...)
+}
+
+// Has reports whether a provider is registered under the name
+func (r *Registry) Has(name string) bool {
+	_, ok := r.providers[name]
+	return ok
+}
+
+// Resolve returns the providers for the given names, or every provider when names is empty
+func (r *Registry) Resolve(names []string) ([]Provider, error) {
+	if len(names) == 0 {
+		names = r.names
+	}
+
+	providers := make([]Provider, 0, len(names))
+	for _, name := range names {
+		provider, ok := r.providers[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown platform %q, available: %s", name, strings.Join(r.names, ", "))
+		}
+		providers = append(providers, provider)
+	}
+	return providers, nil
+}
+
+// Analyze computes the price analysis of a set of listings
+func Analyze(listings []models.ScrapedListing) models.ListingAnalysis {
+	analysis := models.ListingAnalysis{TotalListings: len(listings)}
+	if len(listings) == 0 {
+		return analysis
+	}
+
+	total := 0.0
+	analysis.LowestPrice = listings[0].Price
+	for _, listing := range listings {
+		total += listing.Price
+		if listing.Price > analysis.HighestPrice {
+			analysis.HighestPrice = listing.Price
+		}
+		if listing.Price < analysis.LowestPrice {
+			analysis.LowestPrice = listing.Price
+		}
+	}
+	analysis.AveragePrice = total / float64(len(listings))
+	return analysis
+}
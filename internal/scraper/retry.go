@@ -0,0 +1,61 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+)
+
+// RetryPolicy controls how a failed platform scrape is retried
+type RetryPolicy struct {
+	// Attempts is the total number of attempts, at least one
+	Attempts int
+	// BaseDelay is the wait before the first retry; each further retry doubles it
+	BaseDelay time.Duration
+	// MaxDelay caps the wait between attempts
+	MaxDelay time.Duration
+}
+
+// delay returns the wait before the given retry, starting at one
+func (p RetryPolicy) delay(retry int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < retry; i++ {
+		delay *= 2
+		if p.MaxDelay > 0 && delay >= p.MaxDelay {
+			return p.MaxDelay
+		}
+	}
+	return delay
+}
+
+// retryable reports whether an error is worth another attempt. Timeouts, cancellations and
+// suspicious output are not: a scrape that hit its deadline would most likely hit it again,
+// and a broken parser breaks the same way every time.
+func retryable(err error) bool {
+	var anomaly *AnomalyError
+	return !errors.Is(err, ErrTimeout) && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) &&
+		!errors.As(err, &anomaly)
+}
+
+// do calls fn until it succeeds, fails with a non-retryable error or runs out of attempts,
+// waiting with exponential backoff in between. It returns the number of attempts made.
+func (p RetryPolicy) do(ctx context.Context, name string, fn func() error) (int, error) {
+	attempts := max(p.Attempts, 1)
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn()
+		if err == nil || attempt == attempts || !retryable(err) {
+			return attempt, err
+		}
+
+		delay := p.delay(attempt)
+		log.Printf("%s failed (attempt %d/%d), retrying in %s: %v\n", name, attempt, attempts, delay, err)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return attempt, err
+		}
+	}
+}
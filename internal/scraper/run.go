@@ -0,0 +1,108 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/zenha/oliveiras/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// StartRun records the start of a scrape run of the platforms for a query. Storage failures
+// are only logged so that bookkeeping never stops a scrape.
+func (s *Service) StartRun(query models.SearchQuery, platforms []string, triggeredBy string) *models.ScrapeRun {
+	run := &models.ScrapeRun{
+		ID:          primitive.NewObjectID(),
+		Query:       query,
+		Platforms:   platforms,
+		TriggeredBy: triggeredBy,
+		StartedAt:   time.Now(),
+		Status:      models.RunRunning,
+		Results:     []models.RunResult{},
+	}
+	if s.store != nil {
+		if err := s.store.InsertScrapeRun(run); err != nil {
+			log.Printf("Failed to record scrape run %s: %v\n", run.ID.Hex(), err)
+		}
+	}
+	return run
+}
+
+// FinishRun records the platform results of a run and its final status. err is the reason
+// the run stopped early, if it did; results then only holds the platforms that finished.
+func (s *Service) FinishRun(run *models.ScrapeRun, results []PlatformAnalysis, err error) {
+	run.EndedAt = time.Now()
+	run.Results = make([]models.RunResult, 0, len(results))
+
+	for _, result := range results {
+		entry := models.RunResult{
+			Platform: result.Platform,
+			Listings: len(result.Listings),
+			Rejected: result.Rejected,
+			Skipped:  result.Skipped,
+			Usage:    result.Usage,
+			Attempts: result.Attempts,
+			Cached:   result.Cached,
+		}
+		if result.RunID != run.ID {
+			entry.SourceRun = result.RunID
+		}
+		if result.Err != nil {
+			entry.Error = result.Err.Error()
+		}
+		var anomaly *AnomalyError
+		if errors.As(result.Err, &anomaly) {
+			entry.Anomalies = anomaly.Anomalies
+		}
+		run.Results = append(run.Results, entry)
+	}
+	summarizeRun(run, err)
+
+	if s.store != nil {
+		if err := s.store.UpdateScrapeRun(run); err != nil {
+			log.Printf("Failed to update scrape run %s: %v\n", run.ID.Hex(), err)
+		}
+	}
+}
+
+// summarizeRun totals the platform results of a run and sets its status from them. err is the
+// reason the run stopped early, if it did; a cancelled or interrupted run keeps that status.
+func summarizeRun(run *models.ScrapeRun, err error) {
+	run.Listings, run.Rejected = 0, 0
+	run.Suspicious = false
+	failed := 0
+	for _, result := range run.Results {
+		if result.Error != "" {
+			failed++
+		}
+		if len(result.Anomalies) > 0 {
+			run.Suspicious = true
+		}
+		run.Listings += result.Listings
+		run.Rejected += result.Rejected
+	}
+
+	switch {
+	case run.Status == models.RunCancelled || run.Status == models.RunInterrupted:
+		// Reprocessing does not bring back the platforms a run stopped early never finished
+	case errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+		run.Status = models.RunCancelled
+	case err != nil || (failed > 0 && failed == len(run.Results)):
+		run.Status = models.RunFailed
+	case failed > 0:
+		run.Status = models.RunPartial
+	default:
+		run.Status = models.RunSucceeded
+	}
+}
+
+// providerNames returns the platform names of the providers
+func providerNames(providers []Provider) []string {
+	names := make([]string, 0, len(providers))
+	for _, provider := range providers {
+		names = append(names, provider.Name())
+	}
+	return names
+}
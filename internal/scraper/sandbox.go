@@ -0,0 +1,113 @@
+package scraper
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/zenha/oliveiras/internal/models"
+)
+
+// SandboxExecCommand is the subcommand of the bot's binary that applies the limits to a
+// scraper process before running the script
+const SandboxExecCommand = "sandbox-exec"
+
+// baseEnv are the environment variables scraper processes inherit; everything else,
+// including the database and bot credentials, is withheld
+var baseEnv = []string{"PATH", "LANG", "LC_ALL", "TZ", "PYTHONPATH", "VIRTUAL_ENV", "PLAYWRIGHT_BROWSERS_PATH", "DISPLAY"}
+
+// Limits are the resource limits applied to each scraper process. Zero leaves a limit unset.
+type Limits struct {
+	// MemoryBytes caps the data segment, which covers the heap of the script and its browsers
+	MemoryBytes uint64
+	// CPUSeconds caps the CPU time of each process, per request for workers
+	CPUSeconds uint64
+	// OpenFiles caps the open file descriptors of each process
+	OpenFiles uint64
+	// Processes caps the processes the scraper may start on top of those the user running
+	// the bot already has
+	Processes uint64
+}
+
+// Sandbox describes how scraper processes are isolated: resource limits, a private working
+// directory per process and a minimal environment
+type Sandbox struct {
+	Limits Limits
+	// WorkDir holds the private working directories
+	WorkDir string
+	// Env names extra environment variables passed to the scraper
+	Env []string
+}
+
+// prepare gives the command a fresh working directory and the minimal environment, and makes
+// it start under the resource limits. perRequestCPU leaves the CPU limit to be renewed for
+// every request of a worker. The returned function removes the directory once the process
+// is done.
+func (s *Sandbox) prepare(cmd *exec.Cmd, perRequestCPU bool) (func(), error) {
+	if s == nil {
+		return func() {}, nil
+	}
+
+	if err := os.MkdirAll(s.WorkDir, 0o700); err != nil {
+		return nil, fmt.Errorf("creating scraper work directory: %v", err)
+	}
+	dir, err := os.MkdirTemp(s.WorkDir, "run-")
+	if err != nil {
+		return nil, fmt.Errorf("creating scraper work directory: %v", err)
+	}
+
+	cmd.Dir = dir
+	cmd.Env = []string{"HOME=" + dir, "TMPDIR=" + dir}
+	for _, name := range append(baseEnv, s.Env...) {
+		if value, ok := os.LookupEnv(name); ok {
+			cmd.Env = append(cmd.Env, name+"="+value)
+		}
+	}
+
+	cleanup := func() {
+		if err := os.RemoveAll(dir); err != nil {
+			log.Println("Failed to remove scraper work directory:", err)
+		}
+	}
+	if err := wrapLimits(cmd, s.Limits, perRequestCPU); err != nil {
+		cleanup()
+		return nil, err
+	}
+	return cleanup, nil
+}
+
+// renewCPU gives a worker's processes a fresh CPU time allowance for its next request
+func (s *Sandbox) renewCPU(pid int) error {
+	if s == nil || s.Limits.CPUSeconds == 0 {
+		return nil
+	}
+	return renewCPULimit(pid, s.Limits.CPUSeconds)
+}
+
+// processUsage reports what a finished scraper process consumed, logging it too
+func processUsage(name string, state *os.ProcessState) *models.ResourceUsage {
+	if state == nil {
+		return nil
+	}
+	usage, ok := resourceUsage(state)
+	if !ok {
+		return nil
+	}
+	log.Printf("%s used %s\n", name, usage)
+	return &usage
+}
+
+// absolutePath resolves paths relative to the bot's directory, since scraper processes run in
+// their own working directory. Bare command names are left for PATH lookup.
+func absolutePath(path string) string {
+	if path == "" || filepath.IsAbs(path) || filepath.Base(path) == path {
+		return path
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	return abs
+}
@@ -0,0 +1,273 @@
+package scraper
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/zenha/oliveiras/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Priority orders queued scrapes; higher priorities run first and equal ones in FIFO order
+type Priority int
+
+// Scrape priorities
+const (
+	PriorityLow Priority = iota
+	PriorityNormal
+	PriorityHigh
+)
+
+// ScrapeOptions tune a scheduled scrape
+type ScrapeOptions struct {
+	Priority Priority
+	// Force scrapes again even when a recent scrape of the same query can be reused
+	Force bool
+	// TriggeredBy tells who or what asked for the scrape, recorded on its run
+	TriggeredBy string
+	// DryRun scrapes without storing listings, recording a run or scrape, or feeding the
+	// anomaly history and alerts. It is never answered from or shared with other scrapes.
+	DryRun bool
+}
+
+// Scheduler queues scrapes in front of the service. It caps how many scrapes run at once,
+// overall and per platform, and coalesces identical pending or running scrapes so every
+// caller receives the same result.
+type Scheduler struct {
+	service        *Service
+	maxRunning     int
+	platformLimits map[string]int
+
+	mu                sync.Mutex
+	queue             []*job
+	jobs              map[string]*job
+	running           int
+	runningByPlatform map[string]int
+	seq               uint64
+}
+
+// job is one platform scrape shared by every caller asking for the same query
+type job struct {
+	key      string
+	platform string
+	query    models.SearchQuery
+	runID    primitive.ObjectID
+	dryRun   bool
+	priority Priority
+	seq      uint64
+	started  bool
+	waiters  int
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+	result PlatformAnalysis
+}
+
+// NewScheduler creates a scheduler running at most maxRunning scrapes at once. platformLimits
+// caps individual platforms further; platforms without a limit only share the global cap.
+func NewScheduler(service *Service, maxRunning int, platformLimits map[string]int) *Scheduler {
+	if maxRunning < 1 {
+		maxRunning = 1
+	}
+	return &Scheduler{
+		service:           service,
+		maxRunning:        maxRunning,
+		platformLimits:    platformLimits,
+		jobs:              make(map[string]*job),
+		runningByPlatform: make(map[string]int),
+	}
+}
+
+// Platforms returns the names of the platforms the scheduler can scrape
+func (s *Scheduler) Platforms() []string {
+	return s.service.Platforms()
+}
+
+// IsPlatform reports whether name is a platform the scheduler can scrape
+func (s *Scheduler) IsPlatform(name string) bool {
+	return s.service.IsPlatform(name)
+}
+
+// Scrape queues a scrape of the given platforms, or all of them when platforms is empty,
+// and waits for every platform to finish. Platforms scraped recently for the same query are
+// answered from stored data unless opts.Force is set. Platform failures are reported in the
+// results. If ctx ends first the caller stops waiting, and scrapes nobody else is waiting for
+// are cancelled. Every call except a dry run is recorded as a scrape run.
+func (s *Scheduler) Scrape(ctx context.Context, query models.SearchQuery, platforms []string, opts ScrapeOptions) ([]PlatformAnalysis, error) {
+	providers, err := s.service.registry.Resolve(platforms)
+	if err != nil {
+		return nil, err
+	}
+
+	run := &models.ScrapeRun{}
+	if !opts.DryRun {
+		run = s.service.StartRun(query, providerNames(providers), opts.TriggeredBy)
+	}
+	finished := make(map[string]PlatformAnalysis, len(providers))
+	jobs := make([]*job, 0, len(providers))
+	for _, provider := range providers {
+		if !opts.Force && !opts.DryRun {
+			if result, ok := s.service.Cached(query, provider.Name()); ok {
+				finished[provider.Name()] = result
+				continue
+			}
+		}
+		jobs = append(jobs, s.submit(provider.Name(), query, run.ID, opts.Priority, opts.DryRun))
+	}
+	defer func() {
+		for _, j := range jobs {
+			s.release(j)
+		}
+	}()
+
+	for _, j := range jobs {
+		select {
+		case <-j.done:
+			finished[j.platform] = j.result
+		case <-ctx.Done():
+			if !opts.DryRun {
+				s.service.FinishRun(run, inOrder(providers, finished), ctx.Err())
+			}
+			return nil, ctx.Err()
+		}
+	}
+
+	results := inOrder(providers, finished)
+	if !opts.DryRun {
+		s.service.FinishRun(run, results, nil)
+	}
+	return results, nil
+}
+
+// inOrder returns the finished results in the registry order of the providers, whether a
+// platform was cached or scraped
+func inOrder(providers []Provider, finished map[string]PlatformAnalysis) []PlatformAnalysis {
+	results := make([]PlatformAnalysis, 0, len(finished))
+	for _, provider := range providers {
+		if result, ok := finished[provider.Name()]; ok {
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+// submit attaches the caller to an identical pending or running job, or queues a new one
+// whose listings are stored under runID. Dry runs only share jobs with other dry runs.
+func (s *Scheduler) submit(platform string, query models.SearchQuery, runID primitive.ObjectID, priority Priority, dryRun bool) *job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := platform + "|" + query.Key()
+	if dryRun {
+		key = "dry|" + key
+	}
+	if j, ok := s.jobs[key]; ok {
+		j.waiters++
+		if !j.started && priority > j.priority {
+			j.priority = priority
+			s.sortQueue()
+		}
+		return j
+	}
+
+	s.seq++
+	ctx, cancel := context.WithCancel(context.Background())
+	j := &job{
+		key:      key,
+		platform: platform,
+		query:    query,
+		runID:    runID,
+		dryRun:   dryRun,
+		priority: priority,
+		seq:      s.seq,
+		waiters:  1,
+		ctx:      ctx,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+	s.jobs[key] = j
+	s.queue = append(s.queue, j)
+	s.sortQueue()
+	s.dispatch()
+	return j
+}
+
+// release detaches a caller from a job, cancelling the job when nobody waits for it anymore.
+// A cancelled job no longer takes new callers, so an identical scrape submitted while it winds
+// down starts a job of its own.
+func (s *Scheduler) release(j *job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j.waiters--
+	if j.waiters > 0 || s.jobs[j.key] != j {
+		return
+	}
+
+	if j.started {
+		delete(s.jobs, j.key)
+		j.cancel()
+		return
+	}
+
+	// Still queued: drop it without running
+	for i, queued := range s.queue {
+		if queued == j {
+			s.queue = append(s.queue[:i], s.queue[i+1:]...)
+			break
+		}
+	}
+	delete(s.jobs, j.key)
+	j.result = PlatformAnalysis{Platform: j.platform, Err: context.Canceled}
+	j.cancel()
+	close(j.done)
+}
+
+// sortQueue orders the queue by priority, then by arrival
+func (s *Scheduler) sortQueue() {
+	sort.SliceStable(s.queue, func(a, b int) bool {
+		if s.queue[a].priority != s.queue[b].priority {
+			return s.queue[a].priority > s.queue[b].priority
+		}
+		return s.queue[a].seq < s.queue[b].seq
+	})
+}
+
+// dispatch starts queued jobs while the limits allow. A job whose platform is at its limit
+// does not block jobs for other platforms behind it. Must be called with s.mu held.
+func (s *Scheduler) dispatch() {
+	for i := 0; i < len(s.queue) && s.running < s.maxRunning; {
+		j := s.queue[i]
+		if limit, ok := s.platformLimits[j.platform]; ok && s.runningByPlatform[j.platform] >= limit {
+			i++
+			continue
+		}
+
+		s.queue = append(s.queue[:i], s.queue[i+1:]...)
+		j.started = true
+		s.running++
+		s.runningByPlatform[j.platform]++
+		go s.run(j)
+	}
+}
+
+// run scrapes a job and hands the result to every caller waiting for it
+func (s *Scheduler) run(j *job) {
+	result := s.service.ScrapePlatform(j.ctx, j.runID, j.query, j.platform, j.dryRun)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j.result = result
+	j.cancel()
+	s.running--
+	s.runningByPlatform[j.platform]--
+	// A cancelled job may already have been replaced by a new one for the same key
+	if s.jobs[j.key] == j {
+		delete(s.jobs, j.key)
+	}
+	close(j.done)
+	s.dispatch()
+}
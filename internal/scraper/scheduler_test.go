@@ -0,0 +1,224 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zenha/oliveiras/internal/models"
+)
+
+// heldScrape is a scrape the blocking provider has started, finished by closing proceed
+type heldScrape struct {
+	ctx     context.Context
+	proceed chan struct{}
+}
+
+// blockingProvider holds every scrape until the test lets it proceed, then fails it if its
+// context was cancelled in the meantime
+type blockingProvider struct {
+	started chan heldScrape
+
+	mu    sync.Mutex
+	calls int
+}
+
+func newBlockingProvider() *blockingProvider {
+	return &blockingProvider{started: make(chan heldScrape, 4)}
+}
+
+func (p *blockingProvider) Name() string {
+	return "airbnb"
+}
+
+func (p *blockingProvider) Scrape(ctx context.Context, query models.SearchQuery) (*Output, error) {
+	p.mu.Lock()
+	p.calls++
+	p.mu.Unlock()
+
+	held := heldScrape{ctx: ctx, proceed: make(chan struct{})}
+	p.started <- held
+	<-held.proceed
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return &Output{}, nil
+}
+
+func (p *blockingProvider) callCount() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.calls
+}
+
+// scrapeOutcome is what a Scrape call returned
+type scrapeOutcome struct {
+	results []PlatformAnalysis
+	err     error
+}
+
+var schedulerQuery = models.SearchQuery{
+	StartDate: "2026-07-10",
+	EndDate:   "2026-07-12",
+	Market:    models.Market{Location: "Ericeira", Guests: 2},
+}
+
+func newTestScheduler(provider Provider) *Scheduler {
+	return NewScheduler(NewService(NewRegistry(provider), nil, RetryPolicy{Attempts: 1}), 2, nil)
+}
+
+// startScrape runs a scrape in the background
+func startScrape(ctx context.Context, scheduler *Scheduler) <-chan scrapeOutcome {
+	outcome := make(chan scrapeOutcome, 1)
+	go func() {
+		results, err := scheduler.Scrape(ctx, schedulerQuery, nil, ScrapeOptions{Priority: PriorityNormal})
+		outcome <- scrapeOutcome{results, err}
+	}()
+	return outcome
+}
+
+func nextScrape(t *testing.T, provider *blockingProvider) heldScrape {
+	t.Helper()
+	select {
+	case held := <-provider.started:
+		return held
+	case <-time.After(2 * time.Second):
+		t.Fatal("the provider was not called")
+		return heldScrape{}
+	}
+}
+
+func outcomeOf(t *testing.T, outcome <-chan scrapeOutcome) scrapeOutcome {
+	t.Helper()
+	select {
+	case result := <-outcome:
+		return result
+	case <-time.After(2 * time.Second):
+		t.Fatal("the scrape did not return")
+		return scrapeOutcome{}
+	}
+}
+
+// waiters returns how many callers wait for the pending or running job of the query
+func waiters(scheduler *Scheduler) int {
+	scheduler.mu.Lock()
+	defer scheduler.mu.Unlock()
+	if j, ok := scheduler.jobs["airbnb|"+schedulerQuery.Key()]; ok {
+		return j.waiters
+	}
+	return 0
+}
+
+func waitForWaiters(t *testing.T, scheduler *Scheduler, count int) {
+	t.Helper()
+	waitFor(t, fmt.Sprintf("%d waiters", count), func() bool {
+		return waiters(scheduler) == count
+	})
+}
+
+// waitFor polls until done reports true
+func waitFor(t *testing.T, what string, done func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !done() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func expectSucceeded(t *testing.T, outcome scrapeOutcome) {
+	t.Helper()
+	if outcome.err != nil {
+		t.Fatal(outcome.err)
+	}
+	if len(outcome.results) != 1 || outcome.results[0].Err != nil {
+		t.Fatalf("expected one successful result, got %+v", outcome.results)
+	}
+}
+
+func TestSchedulerCoalescesIdenticalScrapes(t *testing.T) {
+	provider := newBlockingProvider()
+	scheduler := newTestScheduler(provider)
+
+	first := startScrape(context.Background(), scheduler)
+	held := nextScrape(t, provider)
+	second := startScrape(context.Background(), scheduler)
+	waitForWaiters(t, scheduler, 2)
+
+	close(held.proceed)
+	expectSucceeded(t, outcomeOf(t, first))
+	expectSucceeded(t, outcomeOf(t, second))
+	if calls := provider.callCount(); calls != 1 {
+		t.Errorf("expected one scrape for both callers, got %d", calls)
+	}
+	if count := waiters(scheduler); count != 0 {
+		t.Errorf("expected the finished job to be dropped, %d waiters left", count)
+	}
+}
+
+func TestSchedulerKeepsWaitedForScrapesRunning(t *testing.T) {
+	provider := newBlockingProvider()
+	scheduler := newTestScheduler(provider)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	leaving := startScrape(ctx, scheduler)
+	held := nextScrape(t, provider)
+	staying := startScrape(context.Background(), scheduler)
+	waitForWaiters(t, scheduler, 2)
+
+	cancel()
+	if outcome := outcomeOf(t, leaving); !errors.Is(outcome.err, context.Canceled) {
+		t.Fatalf("expected the leaving caller to be cancelled, got %v", outcome.err)
+	}
+	if held.ctx.Err() != nil {
+		t.Fatal("the scrape was cancelled while another caller waited for it")
+	}
+
+	close(held.proceed)
+	expectSucceeded(t, outcomeOf(t, staying))
+}
+
+func TestSchedulerResubmitsWhileCancelledScrapeWindsDown(t *testing.T) {
+	provider := newBlockingProvider()
+	scheduler := newTestScheduler(provider)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelled := startScrape(ctx, scheduler)
+	old := nextScrape(t, provider)
+	cancel()
+	if outcome := outcomeOf(t, cancelled); !errors.Is(outcome.err, context.Canceled) {
+		t.Fatalf("expected the caller to be cancelled, got %v", outcome.err)
+	}
+	if old.ctx.Err() == nil {
+		t.Fatal("the scrape nobody waits for was not cancelled")
+	}
+
+	// The cancelled scrape is still running; an identical scrape must not attach to it
+	resubmitted := startScrape(context.Background(), scheduler)
+	current := nextScrape(t, provider)
+	if current.ctx.Err() != nil {
+		t.Fatal("the resubmitted scrape reused the cancelled one")
+	}
+
+	// The cancelled scrape finishing must not drop the new job, which still coalesces
+	close(old.proceed)
+	waitFor(t, "the cancelled scrape to finish", func() bool {
+		scheduler.mu.Lock()
+		defer scheduler.mu.Unlock()
+		return scheduler.running == 1
+	})
+	joined := startScrape(context.Background(), scheduler)
+	waitForWaiters(t, scheduler, 2)
+
+	close(current.proceed)
+	expectSucceeded(t, outcomeOf(t, resubmitted))
+	expectSucceeded(t, outcomeOf(t, joined))
+	if calls := provider.callCount(); calls != 2 {
+		t.Errorf("expected 2 scrapes, got %d", calls)
+	}
+}
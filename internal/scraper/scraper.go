@@ -1,64 +1,387 @@
 package scraper
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"log"
-	"os/exec"
-	"strings"
+	"time"
 
 	"github.com/zenha/oliveiras/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
-// Service handles scraping operations
+// ErrTimeout is returned when a scraper run exceeds its timeout
+var ErrTimeout = errors.New("scraper timed out")
+
+// PlatformAnalysis holds the outcome of scraping one platform: its listings and price
+// analysis, or the error that made it fail
+type PlatformAnalysis struct {
+	Platform string
+	Listings []models.ScrapedListing
+	Analysis models.ListingAnalysis
+	// Rejected counts the listings that failed validation
+	Rejected int
+	// Skipped counts the malformed scraper records left out
+	Skipped int
+	// Usage is what the scraper consumed for the platform
+	Usage *models.ResourceUsage
+	// Attempts counts the scraper runs, including retries
+	Attempts int
+	// Err is set when the platform failed after every attempt
+	Err error
+	// Cached is set when the result was read from a recent scrape instead of scraping again
+	Cached bool
+	// ScrapedAt is when the listings were scraped
+	ScrapedAt time.Time
+	// RunID is the scrape run that stored the listings
+	RunID primitive.ObjectID
+	// Diff compares the listings with the previous scrape of the same query; nil when there
+	// is nothing to compare with or the result was cached
+	Diff *ListingDiff
+}
+
+// Store persists the listings the service scrapes
+type Store interface {
+	// UpsertListings stores listings found by a query in a run, replacing those the same query
+	// found with the same URL and stay dates
+	UpsertListings(platform string, query models.SearchQuery, runID primitive.ObjectID, listings []models.ScrapedListing, insertedAt time.Time) error
+	// GetQueryListingsSince returns the listings a query found for a stay inserted since a time
+	GetQueryListingsSince(platform, queryKey, startDate, endDate string, since time.Time) ([]models.ScrapedListing, error)
+	// GetRunListings returns the listings of a stay that a run stored
+	GetRunListings(platform string, runID primitive.ObjectID, startDate, endDate string) ([]models.ScrapedListing, error)
+	// InsertScrapeRecord notes a successful platform scrape
+	InsertScrapeRecord(record *models.ScrapeRecord) error
+	// UpdateScrapeRecord stores the current state of a platform scrape
+	UpdateScrapeRecord(record *models.ScrapeRecord) error
+	// GetLatestScrapeRecord returns the most recent sane scrape of a platform for a query key, or nil
+	GetLatestScrapeRecord(platform, queryKey string) (*models.ScrapeRecord, error)
+	// GetRecentScrapeRecords returns up to limit recent sane scrapes of a platform for a query key
+	GetRecentScrapeRecords(platform, queryKey string, limit int) ([]models.ScrapeRecord, error)
+	// InsertScrapeRun stores a scrape run when it starts
+	InsertScrapeRun(run *models.ScrapeRun) error
+	// UpdateScrapeRun stores the current state of a scrape run
+	UpdateScrapeRun(run *models.ScrapeRun) error
+	// GetScrapeRun returns a scrape run by id, or nil when there is none
+	GetScrapeRun(id primitive.ObjectID) (*models.ScrapeRun, error)
+}
+
+// Service handles scraping operations across the registered platforms
 type Service struct {
-	pythonPath string
-	scriptPath string
+	registry *Registry
+	store    Store
+	retry    RetryPolicy
+	// cacheAge is how recent a scrape must be to be reused; zero disables reuse
+	cacheAge time.Duration
+	// archive keeps the raw output of every scrape when set
+	archive *Archive
+	// onAnomaly is told about suspicious scrapes when set
+	onAnomaly func(AnomalyAlert)
+}
+
+// NewService creates a new scraper service storing what it scrapes in store and retrying
+// failed platforms according to retry
+func NewService(registry *Registry, store Store, retry RetryPolicy) *Service {
+	return &Service{registry: registry, store: store, retry: retry}
+}
+
+// SetArchive makes the service keep the raw output of every scrape in the archive
+func (s *Service) SetArchive(archive *Archive) {
+	s.archive = archive
 }
 
-// NewService creates a new scraper service
-func NewService(pythonPath, scriptPath string) *Service {
-	return &Service{
-		pythonPath: pythonPath,
-		scriptPath: scriptPath,
+// SetCacheAge makes the service reuse scrapes of the same query younger than age
+func (s *Service) SetCacheAge(age time.Duration) {
+	s.cacheAge = age
+}
+
+// Platforms returns the names of the platforms the service can scrape
+func (s *Service) Platforms() []string {
+	return s.registry.Names()
+}
+
+// IsPlatform reports whether name is a platform the service can scrape
+func (s *Service) IsPlatform(name string) bool {
+	return s.registry.Has(name)
+}
+
+// ScrapePlatform scrapes a single platform for a run, retrying failures with exponential
+// backoff, and analyzes its listings. A dry run stores and records nothing.
+func (s *Service) ScrapePlatform(ctx context.Context, runID primitive.ObjectID, query models.SearchQuery, platform string, dryRun bool) PlatformAnalysis {
+	providers, err := s.registry.Resolve([]string{platform})
+	if err != nil {
+		return PlatformAnalysis{Platform: platform, Err: err}
 	}
+
+	var result PlatformAnalysis
+	attempts, err := s.retry.do(ctx, platform+" scrape", func() error {
+		var err error
+		result, err = s.scrapeProvider(ctx, runID, providers[0], query, dryRun)
+		return err
+	})
+	result.Platform = platform
+	result.RunID = runID
+	result.Attempts = attempts
+	result.Err = err
+	return result
 }
 
-// ScrapeListings scrapes both Airbnb and Booking.com listings
-func (s *Service) ScrapeListings(startDate, endDate string) (*models.ListingAnalysis, *models.ListingAnalysis, error) {
-	cmd := exec.Command(s.pythonPath, s.scriptPath, startDate, endDate)
-	output, err := cmd.CombinedOutput()
+// Cached returns the stored result of a scrape of the platform for the same query that is
+// younger than the cache age, if there is one. Both the scrape and its listings are looked
+// up by the query key, so another market's listings for the same dates never answer it.
+func (s *Service) Cached(query models.SearchQuery, platform string) (PlatformAnalysis, bool) {
+	if s.store == nil || s.cacheAge <= 0 {
+		return PlatformAnalysis{}, false
+	}
+
+	record, err := s.store.GetLatestScrapeRecord(platform, query.Key())
+	if err != nil {
+		log.Printf("Failed to look up recent %s scrape: %v\n", platform, err)
+		return PlatformAnalysis{}, false
+	}
+	if record == nil || time.Since(record.StartedAt) > s.cacheAge {
+		return PlatformAnalysis{}, false
+	}
+
+	listings, err := s.store.GetQueryListingsSince(platform, query.Key(), query.StartDate, query.EndDate, record.StartedAt)
 	if err != nil {
-		log.Println("cmd.CombinedOutput() failed:", err)
-		log.Println("Output:", string(output))
-		return nil, nil, err
+		log.Printf("Failed to read recent %s listings: %v\n", platform, err)
+		return PlatformAnalysis{}, false
 	}
-	
-	return s.parseScrapingResults(string(output))
+	return PlatformAnalysis{
+		Platform:  platform,
+		Listings:  listings,
+		Analysis:  Analyze(listings),
+		Cached:    true,
+		ScrapedAt: record.StartedAt,
+		RunID:     record.RunID,
+	}, true
 }
 
-// parseScrapingResults parses the Python script output
-func (s *Service) parseScrapingResults(output string) (*models.ListingAnalysis, *models.ListingAnalysis, error) {
-	finalOutputSplit := strings.Split(output, "Airbnb Listings Data:")
-	bookingStart := strings.Index(finalOutputSplit[1], "Booking Listings Data:")
-	airbnbData := strings.TrimSpace(finalOutputSplit[1][:bookingStart])
-	bookingData := strings.TrimSpace(finalOutputSplit[1][bookingStart:])
-	bookingData = strings.ReplaceAll(bookingData, "Booking Listings Data:", "")
+// Failed returns the results of the platforms that failed
+func Failed(results []PlatformAnalysis) []PlatformAnalysis {
+	failed := []PlatformAnalysis{}
+	for _, result := range results {
+		if result.Err != nil {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// scrapeProvider runs one provider, validates and stores what it returns, and analyzes
+// the listings stored by this run. A dry run analyzes the validated listings without storing,
+// archiving or checking them for anomalies.
+func (s *Service) scrapeProvider(ctx context.Context, runID primitive.ObjectID, provider Provider, query models.SearchQuery, dryRun bool) (PlatformAnalysis, error) {
+	started := time.Now()
+	output, err := provider.Scrape(ctx, query)
+	if err != nil {
+		return PlatformAnalysis{}, err
+	}
+
+	result := PlatformAnalysis{Platform: provider.Name(), RunID: runID, Skipped: output.Skipped, Usage: output.Usage}
+	listings, rejected := normalizeListings(provider.Name(), output.Listings, query)
+	result.Rejected = rejected
+
+	if s.store != nil && !dryRun {
+		record := &models.ScrapeRecord{
+			ID:        primitive.NewObjectID(),
+			RunID:     runID,
+			Platform:  provider.Name(),
+			QueryKey:  query.Key(),
+			Query:     query,
+			StartedAt: started,
+		}
+		if s.archive != nil && len(output.Raw) > 0 {
+			record.Archive, err = s.archive.Save(provider.Name(), record.ID.Hex(), output.Raw)
+			if err != nil {
+				log.Printf("Failed to archive %s output: %v\n", provider.Name(), err)
+			}
+		}
 
-	airbnbData = strings.ReplaceAll(airbnbData, "'", "\"")
-	bookingData = strings.ReplaceAll(bookingData, "'", "\"")
+		// Suspicious output is recorded and archived but its listings are not stored, so
+		// analyses and prompts keep using the last sane data
+		if anomalies := s.detectAnomalies(provider.Name(), query, listings, rejected); len(anomalies) > 0 {
+			record.Listings = len(listings)
+			record.Snapshot = Snapshot(listings)
+			record.Suspicious = true
+			record.Anomalies = anomalies
+			s.insertScrapeRecord(record)
+			if s.onAnomaly != nil {
+				s.onAnomaly(AnomalyAlert{Platform: provider.Name(), Query: query, RunID: runID, Anomalies: anomalies})
+			}
+			return PlatformAnalysis{}, &AnomalyError{Anomalies: anomalies}
+		}
 
-	var airbnbAnalysis models.ListingAnalysis
-	var bookingAnalysis models.ListingAnalysis
+		if err := s.store.UpsertListings(provider.Name(), query, runID, listings, time.Now()); err != nil {
+			return PlatformAnalysis{}, fmt.Errorf("storing %s listings: %v", provider.Name(), err)
+		}
+		// The analysis and the snapshot only cover the listings this run stored for the query's
+		// stay; listings scraped for a different stay are stored but not analyzed
+		listings, err = s.store.GetRunListings(provider.Name(), runID, query.StartDate, query.EndDate)
+		if err != nil {
+			return PlatformAnalysis{}, fmt.Errorf("reading stored %s listings: %v", provider.Name(), err)
+		}
 
-	if err := json.Unmarshal([]byte(airbnbData), &airbnbAnalysis); err != nil {
-		log.Println("Airbnb json.Unmarshal() failed:", err)
-		return nil, nil, err
+		record.Listings = len(listings)
+		record.Snapshot = Snapshot(listings)
+		result.Diff = s.diffWithPrevious(provider.Name(), query, record.Snapshot)
+		s.insertScrapeRecord(record)
 	}
 
-	if err := json.Unmarshal([]byte(bookingData), &bookingAnalysis); err != nil {
-		log.Println("Booking json.Unmarshal() failed:", err)
-		return nil, nil, err
+	result.ScrapedAt = started
+	result.Listings = listings
+	result.Analysis = Analyze(listings)
+	return result, nil
+}
+
+// insertScrapeRecord stores a scrape record, only logging failures
+func (s *Service) insertScrapeRecord(record *models.ScrapeRecord) {
+	if err := s.store.InsertScrapeRecord(record); err != nil {
+		log.Printf("Failed to record %s scrape: %v\n", record.Platform, err)
 	}
+}
 
-	return &airbnbAnalysis, &bookingAnalysis, nil
+// diffWithPrevious compares a snapshot with the latest stored scrape of the platform for the
+// query. Scrapes recorded before snapshots were kept are not compared.
+func (s *Service) diffWithPrevious(platform string, query models.SearchQuery, snapshot []models.ListingPrice) *ListingDiff {
+	previous, err := s.store.GetLatestScrapeRecord(platform, query.Key())
+	if err != nil {
+		log.Printf("Failed to look up previous %s scrape: %v\n", platform, err)
+		return nil
+	}
+	if previous == nil || (previous.Listings > 0 && len(previous.Snapshot) == 0) {
+		return nil
+	}
+
+	diff := CompareSnapshots(previous.Snapshot, snapshot)
+	diff.Since = previous.StartedAt
+	return &diff
+}
+
+// Reprocess decodes the archived output of a scrape again and rewrites its listings with
+// the current normalization. Listings keep the original scrape time, so reprocess records
+// oldest first to let newer scrapes win. Scrapes found suspicious are checked again and only
+// stored once their output looks sane. The scrape's snapshot and counts and its run's results
+// are updated to match. It returns the listings counted for the scrape's stay and the number
+// rejected.
+func (s *Service) Reprocess(record models.ScrapeRecord) (int, int, error) {
+	if s.archive == nil || s.store == nil {
+		return 0, 0, errors.New("reprocessing needs an archive and a store")
+	}
+	if record.Archive == "" {
+		return 0, 0, errors.New("scrape has no archived output")
+	}
+
+	reader, err := s.archive.Open(record.Archive)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer reader.Close()
+
+	listings, rejected, err := DecodeListings(reader, record.Platform, record.Query)
+	if err != nil {
+		return 0, 0, err
+	}
+	if record.Suspicious {
+		if anomalies := s.detectAnomalies(record.Platform, record.Query, listings, rejected); len(anomalies) > 0 {
+			return 0, rejected, &AnomalyError{Anomalies: anomalies}
+		}
+	}
+	if err := s.store.UpsertListings(record.Platform, record.Query, record.RunID, listings, record.StartedAt); err != nil {
+		return 0, 0, fmt.Errorf("storing %s listings: %v", record.Platform, err)
+	}
+
+	// As for a scrape, the counts and the snapshot cover the listings stored for the stay.
+	// Scrapes recorded before runs were kept stored no run id to read them back by, so they
+	// count the decoded listings of the stay instead.
+	stored := stayListings(listings, record.Query)
+	if !record.RunID.IsZero() {
+		stored, err = s.store.GetRunListings(record.Platform, record.RunID, record.Query.StartDate, record.Query.EndDate)
+		if err != nil {
+			return 0, 0, fmt.Errorf("reading stored %s listings: %v", record.Platform, err)
+		}
+	}
+	record.Listings = len(stored)
+	record.Snapshot = Snapshot(stored)
+	record.Suspicious = false
+	record.Anomalies = nil
+	if err := s.store.UpdateScrapeRecord(&record); err != nil {
+		return 0, 0, fmt.Errorf("updating %s scrape: %v", record.Platform, err)
+	}
+	if err := s.updateRunResult(record, rejected); err != nil {
+		return 0, 0, fmt.Errorf("updating run %s: %v", record.RunID.Hex(), err)
+	}
+	return record.Listings, rejected, nil
+}
+
+// stayListings returns the listings for the query's stay
+func stayListings(listings []models.ScrapedListing, query models.SearchQuery) []models.ScrapedListing {
+	stay := make([]models.ScrapedListing, 0, len(listings))
+	for _, listing := range listings {
+		if listing.StartDate == query.StartDate && listing.EndDate == query.EndDate {
+			stay = append(stay, listing)
+		}
+	}
+	return stay
+}
+
+// updateRunResult rewrites the result of a reprocessed scrape in its run and the run's totals.
+// Scrapes recorded before runs were kept have no run to update.
+func (s *Service) updateRunResult(record models.ScrapeRecord, rejected int) error {
+	if record.RunID.IsZero() {
+		return nil
+	}
+	run, err := s.store.GetScrapeRun(record.RunID)
+	if err != nil || run == nil {
+		return err
+	}
+
+	for i := range run.Results {
+		result := &run.Results[i]
+		if result.Platform != record.Platform || !result.SourceRun.IsZero() {
+			continue
+		}
+		result.Listings = record.Listings
+		result.Rejected = rejected
+		// A suspicious scrape whose output is now stored no longer failed
+		if len(result.Anomalies) > 0 {
+			result.Error = ""
+			result.Anomalies = nil
+		}
+	}
+	summarizeRun(run, nil)
+	return s.store.UpdateScrapeRun(run)
+}
+
+// DecodeListings decodes a platform's scraper output and validates its listings the way a
+// scrape does, returning the listings kept and the number rejected
+func DecodeListings(r io.Reader, platform string, query models.SearchQuery) ([]models.ScrapedListing, int, error) {
+	decoded, err := Decode(r, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	result, err := decoded.Platform(platform)
+	if err != nil {
+		return nil, 0, err
+	}
+	listings, rejected := normalizeListings(platform, result.Listings, query)
+	return listings, rejected, nil
+}
+
+// normalizeListings validates and cleans scraped listings, counting the rejected ones
+func normalizeListings(platform string, scraped []models.ScrapedListing, query models.SearchQuery) ([]models.ScrapedListing, int) {
+	listings := make([]models.ScrapedListing, 0, len(scraped))
+	rejected := 0
+	for _, listing := range scraped {
+		normalized, err := NormalizeListing(listing, query)
+		if err != nil {
+			log.Printf("Rejecting %s listing %q: %v\n", platform, listing.URL, err)
+			rejected++
+			continue
+		}
+		listings = append(listings, normalized)
+	}
+	return listings, rejected
 }
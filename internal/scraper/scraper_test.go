@@ -0,0 +1,205 @@
+package scraper
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zenha/oliveiras/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memoryStore keeps what the service stores in memory. Like the database, it only finds
+// listings by run for listings stored with a run id.
+type memoryStore struct {
+	listings []storedListing
+	records  map[primitive.ObjectID]models.ScrapeRecord
+	runs     map[primitive.ObjectID]models.ScrapeRun
+}
+
+type storedListing struct {
+	runID   primitive.ObjectID
+	listing models.ScrapedListing
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		records: make(map[primitive.ObjectID]models.ScrapeRecord),
+		runs:    make(map[primitive.ObjectID]models.ScrapeRun),
+	}
+}
+
+func (m *memoryStore) UpsertListings(platform string, query models.SearchQuery, runID primitive.ObjectID, listings []models.ScrapedListing, insertedAt time.Time) error {
+next:
+	for _, listing := range listings {
+		stored := storedListing{runID: runID, listing: listing}
+		for i, existing := range m.listings {
+			if existing.listing.URL == listing.URL && existing.listing.StartDate == listing.StartDate && existing.listing.EndDate == listing.EndDate {
+				m.listings[i] = stored
+				continue next
+			}
+		}
+		m.listings = append(m.listings, stored)
+	}
+	return nil
+}
+
+func (m *memoryStore) GetQueryListingsSince(platform, queryKey, startDate, endDate string, since time.Time) ([]models.ScrapedListing, error) {
+	return nil, nil
+}
+
+func (m *memoryStore) GetRunListings(platform string, runID primitive.ObjectID, startDate, endDate string) ([]models.ScrapedListing, error) {
+	found := []models.ScrapedListing{}
+	for _, stored := range m.listings {
+		if !stored.runID.IsZero() && stored.runID == runID && stored.listing.StartDate == startDate && stored.listing.EndDate == endDate {
+			found = append(found, stored.listing)
+		}
+	}
+	return found, nil
+}
+
+func (m *memoryStore) InsertScrapeRecord(record *models.ScrapeRecord) error {
+	m.records[record.ID] = *record
+	return nil
+}
+
+func (m *memoryStore) UpdateScrapeRecord(record *models.ScrapeRecord) error {
+	m.records[record.ID] = *record
+	return nil
+}
+
+func (m *memoryStore) GetLatestScrapeRecord(platform, queryKey string) (*models.ScrapeRecord, error) {
+	return nil, nil
+}
+
+func (m *memoryStore) GetRecentScrapeRecords(platform, queryKey string, limit int) ([]models.ScrapeRecord, error) {
+	return nil, nil
+}
+
+func (m *memoryStore) InsertScrapeRun(run *models.ScrapeRun) error {
+	m.runs[run.ID] = *run
+	return nil
+}
+
+func (m *memoryStore) UpdateScrapeRun(run *models.ScrapeRun) error {
+	m.runs[run.ID] = *run
+	return nil
+}
+
+func (m *memoryStore) GetScrapeRun(id primitive.ObjectID) (*models.ScrapeRun, error) {
+	run, ok := m.runs[id]
+	if !ok {
+		return nil, nil
+	}
+	return &run, nil
+}
+
+// reprocessOutput is archived Airbnb output with two listings for the queried stay, one for
+// another stay and one without a price
+var reprocessOutput = strings.Join([]string{
+	`{"v":1,"type":"listing","platform":"airbnb","data":{"url":"https://www.airbnb.com/rooms/1?check_in=2025-01-14","start_date":"2025-01-14","end_date":"2025-01-16","name":"Casa da Oliveira","price":125}}`,
+	`{"v":1,"type":"listing","platform":"airbnb","data":{"url":"https://www.airbnb.com/rooms/2","start_date":"2025-01-14","end_date":"2025-01-16","name":"Sea view loft","price":90}}`,
+	`{"v":1,"type":"listing","platform":"airbnb","data":{"url":"https://www.airbnb.com/rooms/3","start_date":"2025-01-16","end_date":"2025-01-17","name":"Quinta do Vale","price":98.5}}`,
+	`{"v":1,"type":"listing","platform":"airbnb","data":{"url":"https://www.airbnb.com/rooms/4","start_date":"2025-01-14","end_date":"2025-01-16","name":"No price"}}`,
+	`{"v":1,"type":"summary","platform":"airbnb","data":{"total_listings":4}}`,
+}, "\n") + "\n"
+
+var reprocessQuery = models.SearchQuery{StartDate: "2025-01-14", EndDate: "2025-01-16", Market: models.Market{Location: "Ericeira"}}
+
+// newReprocessService archives the reprocess output and returns a service reading it and the
+// scrape record pointing to it
+func newReprocessService(t *testing.T, store *memoryStore, runID primitive.ObjectID) (*Service, models.ScrapeRecord) {
+	t.Helper()
+	archive := NewArchive(t.TempDir())
+	record := models.ScrapeRecord{
+		ID:        primitive.NewObjectID(),
+		RunID:     runID,
+		Platform:  PlatformAirbnb,
+		QueryKey:  reprocessQuery.Key(),
+		Query:     reprocessQuery,
+		StartedAt: time.Date(2025, 1, 10, 9, 0, 0, 0, time.UTC),
+		Listings:  2,
+		Snapshot: []models.ListingPrice{
+			{URL: "https://www.airbnb.com/rooms/1", Price: 120},
+			{URL: "https://www.airbnb.com/rooms/2", Price: 90},
+		},
+	}
+	var err error
+	record.Archive, err = archive.Save(PlatformAirbnb, record.ID.Hex(), []byte(reprocessOutput))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.records[record.ID] = record
+
+	service := NewService(NewRegistry(), store, RetryPolicy{})
+	service.SetArchive(archive)
+	return service, record
+}
+
+func expectReprocessed(t *testing.T, store *memoryStore, record models.ScrapeRecord, counted, rejected int, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counted != 2 || rejected != 1 {
+		t.Errorf("expected 2 listings and 1 rejected, got %d and %d", counted, rejected)
+	}
+
+	updated := store.records[record.ID]
+	if updated.Listings != counted {
+		t.Errorf("expected the scrape to count the %d listings returned, got %d", counted, updated.Listings)
+	}
+	expected := []models.ListingPrice{
+		{URL: "https://www.airbnb.com/rooms/1", Price: 125},
+		{URL: "https://www.airbnb.com/rooms/2", Price: 90},
+	}
+	if len(updated.Snapshot) != len(expected) {
+		t.Fatalf("expected snapshot %v, got %v", expected, updated.Snapshot)
+	}
+	for i := range expected {
+		if updated.Snapshot[i] != expected[i] {
+			t.Errorf("snapshot %d: expected %v, got %v", i, expected[i], updated.Snapshot[i])
+		}
+	}
+	if len(store.listings) != 3 {
+		t.Errorf("expected every valid listing to be stored, got %d", len(store.listings))
+	}
+}
+
+func TestReprocessScrapeWithoutRun(t *testing.T) {
+	store := newMemoryStore()
+	service, record := newReprocessService(t, store, primitive.NilObjectID)
+
+	counted, rejected, err := service.Reprocess(record)
+	expectReprocessed(t, store, record, counted, rejected, err)
+	if len(store.runs) != 0 {
+		t.Errorf("expected no run to be written, got %d", len(store.runs))
+	}
+}
+
+func TestReprocessScrapeOfRun(t *testing.T) {
+	store := newMemoryStore()
+	run := models.ScrapeRun{
+		ID:        primitive.NewObjectID(),
+		Query:     reprocessQuery,
+		Platforms: []string{PlatformAirbnb, PlatformBooking},
+		Status:    models.RunFailed,
+		Results: []models.RunResult{
+			{Platform: PlatformAirbnb, Error: "suspicious output", Anomalies: []string{"median price 5x higher than usual"}},
+			{Platform: PlatformBooking, Error: "blocked by captcha"},
+		},
+	}
+	store.runs[run.ID] = run
+	service, record := newReprocessService(t, store, run.ID)
+
+	counted, rejected, err := service.Reprocess(record)
+	expectReprocessed(t, store, record, counted, rejected, err)
+
+	updated := store.runs[run.ID]
+	if updated.Listings != 2 || updated.Rejected != 1 {
+		t.Errorf("expected the run to total 2 listings and 1 rejected, got %d and %d", updated.Listings, updated.Rejected)
+	}
+	if updated.Status != models.RunPartial {
+		t.Errorf("expected the run to be partial once Airbnb is stored, got %s", updated.Status)
+	}
+}
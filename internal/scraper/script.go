@@ -0,0 +1,167 @@
+package scraper
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+	"time"
+
+	"github.com/zenha/oliveiras/internal/models"
+)
+
+// Script runs the external scraper script
+type Script struct {
+	pythonPath string
+	scriptPath string
+	timeout    time.Duration
+	sandbox    *Sandbox
+}
+
+// NewScript creates a runner for the scraper script. Runs longer than timeout are killed;
+// a zero timeout only relies on the caller's context. A nil sandbox runs the script with
+// the bot's own environment, directory and limits.
+func NewScript(pythonPath, scriptPath string, timeout time.Duration, sandbox *Sandbox) *Script {
+	return &Script{
+		pythonPath: absolutePath(pythonPath),
+		scriptPath: absolutePath(scriptPath),
+		timeout:    timeout,
+		sandbox:    sandbox,
+	}
+}
+
+// Provider returns a provider that scrapes one platform through the script
+func (s *Script) Provider(platform string) Provider {
+	return &scriptProvider{script: s, platform: platform}
+}
+
+// scriptProvider scrapes a single platform by running the script for it
+type scriptProvider struct {
+	script   *Script
+	platform string
+}
+
+func (p *scriptProvider) Name() string {
+	return p.platform
+}
+
+// Scrape runs the script for the provider's platform, passing the whole query as JSON
+// after the positional arguments, and returns the listings with the script's raw output.
+// Cancelling ctx kills the script and every process it started.
+func (p *scriptProvider) Scrape(ctx context.Context, query models.SearchQuery) (*Output, error) {
+	encoded, err := json.Marshal(query)
+	if err != nil {
+		return nil, err
+	}
+	args := []string{p.script.scriptPath, query.StartDate, query.EndDate, p.platform, "--query", string(encoded)}
+
+	result, raw, usage, err := p.script.run(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+
+	platform, err := result.Platform(p.platform)
+	if err != nil {
+		return nil, err
+	}
+	if platform.Summary.TotalListings != len(platform.Listings) {
+		log.Printf("scraper: %s summary reports %d listings but %d were sent\n", p.platform, platform.Summary.TotalListings, len(platform.Listings))
+	}
+	return &Output{Listings: platform.Listings, Raw: raw, Skipped: platform.Skipped + result.Skipped, Usage: usage}, nil
+}
+
+// run executes the scraper script, decoding its stdout records and logging its stderr.
+// It also returns the raw stdout and what the script consumed.
+func (s *Script) run(ctx context.Context, args []string) (*Result, []byte, *models.ResourceUsage, error) {
+	parent, started := ctx, time.Now()
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, s.pythonPath, args...)
+	killProcessGroup(cmd)
+	cmd.WaitDelay = waitDelay
+	cleanup, err := s.sandbox.prepare(cmd, false)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer cleanup()
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, nil, nil, err
+	}
+
+	logDone := make(chan struct{})
+	go func() {
+		logOutput(stderr)
+		close(logDone)
+	}()
+
+	var raw bytes.Buffer
+	result, decodeErr := Decode(io.TeeReader(stdout, &raw), func(platform string, progress ProgressRecord) {
+		log.Printf("scraper: %s progress %d/%d %s\n", platform, progress.Done, progress.Total, progress.Message)
+	})
+	if decodeErr != nil {
+		// Drain the rest of stdout so the script is not blocked writing to it
+		io.Copy(io.Discard, stdout)
+	}
+
+	<-logDone
+	waitErr := cmd.Wait()
+	usage := processUsage("scraper", cmd.ProcessState)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err := timeoutError(parent, s.timeout, started)
+		log.Println(err)
+		return nil, nil, nil, err
+	}
+	if ctx.Err() != nil {
+		return nil, nil, nil, ctx.Err()
+	}
+	if err := waitErr; err != nil {
+		log.Println("scraper exited with error:", err)
+		return nil, nil, nil, fmt.Errorf("scraper failed: %v", err)
+	}
+	if decodeErr != nil {
+		log.Println("Decoding scraper output failed:", decodeErr)
+		return nil, nil, nil, decodeErr
+	}
+	return result, raw.Bytes(), usage, nil
+}
+
+// timeoutError describes a run stopped by a deadline: the scraper timeout when that is what
+// fired, otherwise the caller's deadline, with how long the run had taken
+func timeoutError(parent context.Context, timeout time.Duration, started time.Time) error {
+	if parent.Err() == nil && timeout > 0 {
+		return fmt.Errorf("%w after %s", ErrTimeout, timeout)
+	}
+	return fmt.Errorf("%w: the caller's deadline passed after %s", ErrTimeout, time.Since(started).Round(time.Second))
+}
+
+// logOutput forwards each line the scraper writes to stderr to the log. After a line too long
+// to log it keeps reading until the end, so the scraper is never blocked writing to stderr.
+func logOutput(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxRecordSize)
+	for scanner.Scan() {
+		log.Println("scraper:", scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		log.Println("Discarding the rest of the scraper's stderr:", err)
+		io.Copy(io.Discard, r)
+	}
+}
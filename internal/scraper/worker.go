@@ -0,0 +1,467 @@
+package scraper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zenha/oliveiras/internal/models"
+)
+
+// Requests sent to a worker on its stdin, one JSON object per line:
+//
+//	{"v":1,"id":7,"type":"scrape","data":{"platform":"airbnb","start_date":"2025-01-14","end_date":"2025-01-16","location":"Lisbon","guests":4,...}}
+//	{"v":1,"id":8,"type":"ping"}
+//
+// The worker answers on stdout with protocol records carrying the request id. A scrape
+// ends with the platform's summary or error record, a ping with a pong record.
+
+// Worker request types
+const (
+	RequestScrape = "scrape"
+	RequestPing   = "ping"
+)
+
+// Worker health checking
+const (
+	pingInterval = time.Minute
+	pingTimeout  = 30 * time.Second
+)
+
+// errWorkerExited reports a worker that stopped while a request was in flight
+var errWorkerExited = errors.New("scraper worker exited")
+
+// WorkerRequest is one request written to a worker
+type WorkerRequest struct {
+	Version int            `json:"v"`
+	ID      uint64         `json:"id"`
+	Type    string         `json:"type"`
+	Data    *ScrapeRequest `json:"data,omitempty"`
+}
+
+// ScrapeRequest asks a worker to scrape one platform; the query fields are inlined
+type ScrapeRequest struct {
+	Platform string `json:"platform"`
+	models.SearchQuery
+}
+
+// WorkerPool keeps long-lived scraper processes running the script in worker mode, so the
+// interpreter and browser start once instead of once per scrape. Workers that crash, time
+// out or stop answering pings are replaced.
+type WorkerPool struct {
+	pythonPath string
+	scriptPath string
+	timeout    time.Duration
+	sandbox    *Sandbox
+
+	// idle holds one slot per worker; a nil slot is started on first use
+	idle   chan *worker
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewWorkerPool starts a pool of size workers running the scraper script. Scrapes longer
+// than timeout kill their worker; a zero timeout only relies on the caller's context.
+// Workers run in the sandbox when it is not nil.
+func NewWorkerPool(pythonPath, scriptPath string, size int, timeout time.Duration, sandbox *Sandbox) *WorkerPool {
+	if size < 1 {
+		size = 1
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &WorkerPool{
+		pythonPath: absolutePath(pythonPath),
+		scriptPath: absolutePath(scriptPath),
+		timeout:    timeout,
+		sandbox:    sandbox,
+		idle:       make(chan *worker, size),
+		ctx:        ctx,
+		cancel:     cancel,
+		done:       make(chan struct{}),
+	}
+	for i := 0; i < size; i++ {
+		p.idle <- nil
+	}
+	go p.healthCheck()
+	return p
+}
+
+// Provider returns a provider that scrapes one platform through the pool
+func (p *WorkerPool) Provider(platform string) Provider {
+	return &workerProvider{pool: p, platform: platform}
+}
+
+// Close stops the health checks and every worker
+func (p *WorkerPool) Close() {
+	p.cancel()
+	<-p.done
+	for i := 0; i < cap(p.idle); i++ {
+		if w := <-p.idle; w != nil {
+			w.stop()
+		}
+	}
+}
+
+// workerProvider scrapes a single platform through the worker pool
+type workerProvider struct {
+	pool     *WorkerPool
+	platform string
+}
+
+func (p *workerProvider) Name() string {
+	return p.platform
+}
+
+// Scrape sends the query to an idle worker and waits for the platform's records, returned
+// as raw output too. Cancelling ctx kills the worker, which is restarted for the next scrape.
+func (p *workerProvider) Scrape(ctx context.Context, query models.SearchQuery) (*Output, error) {
+	request := &ScrapeRequest{Platform: p.platform, SearchQuery: query}
+
+	result, raw, usage, err := p.pool.scrape(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	if result.Error != "" {
+		return nil, fmt.Errorf("%s failed: %s", p.platform, result.Error)
+	}
+	if result.Summary.TotalListings != len(result.Listings) {
+		log.Printf("scraper: %s summary reports %d listings but %d were sent\n", p.platform, result.Summary.TotalListings, len(result.Listings))
+	}
+	return &Output{Listings: result.Listings, Raw: raw, Skipped: result.Skipped, Usage: usage}, nil
+}
+
+// scrape runs a scrape request on an idle worker, starting one if needed. The worker's CPU
+// limit is renewed for the request, and what the worker consumed during it is returned.
+func (p *WorkerPool) scrape(ctx context.Context, request *ScrapeRequest) (*PlatformResult, []byte, *models.ResourceUsage, error) {
+	parent, started := ctx, time.Now()
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+	}
+
+	var w *worker
+	select {
+	case w = <-p.idle:
+	case <-ctx.Done():
+		return nil, nil, nil, ctx.Err()
+	}
+	defer func() { p.idle <- w }()
+
+	if w == nil || w.exited() {
+		var err error
+		if w, err = p.start(); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	pid := w.cmd.Process.Pid
+	if err := p.sandbox.renewCPU(pid); err != nil {
+		w.stop()
+		return nil, nil, nil, fmt.Errorf("renewing scraper worker CPU limit: %v", err)
+	}
+	before, measured := groupUsage(pid)
+
+	result, raw, err := w.scrape(ctx, request)
+	if err != nil {
+		// The worker may be halfway through the request; replace it rather than reuse it
+		w.stop()
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err := timeoutError(parent, p.timeout, started)
+			log.Println(err)
+			return nil, nil, nil, err
+		}
+		if ctx.Err() != nil {
+			return nil, nil, nil, ctx.Err()
+		}
+		return nil, nil, nil, err
+	}
+
+	var usage *models.ResourceUsage
+	if measured {
+		usage = usageSince(pid, before)
+	}
+	return result, raw, usage, nil
+}
+
+// usageSince returns the CPU time a worker's process group used since before was measured,
+// with the group's peak memory so far. Processes that exited without being reaped inside the
+// group are not counted.
+func usageSince(pgid int, before models.ResourceUsage) *models.ResourceUsage {
+	after, ok := groupUsage(pgid)
+	if !ok {
+		return nil
+	}
+	return &models.ResourceUsage{
+		User:        max(after.User-before.User, 0),
+		System:      max(after.System-before.System, 0),
+		MaxRSSBytes: after.MaxRSSBytes,
+	}
+}
+
+// healthCheck pings idle workers periodically and stops those that do not answer, so they
+// are restarted on their next use
+func (p *WorkerPool) healthCheck() {
+	defer close(p.done)
+
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-p.ctx.Done():
+			return
+		}
+
+	slots:
+		for i := 0; i < cap(p.idle); i++ {
+			var w *worker
+			select {
+			case w = <-p.idle:
+			default:
+				// Every other worker is busy
+				break slots
+			}
+			p.idle <- p.check(w)
+		}
+	}
+}
+
+// check pings a worker, replacing it when it crashed or does not answer. Slots that were
+// never used stay empty.
+func (p *WorkerPool) check(w *worker) *worker {
+	if w == nil {
+		return nil
+	}
+	if !w.exited() {
+		ctx, cancel := context.WithTimeout(p.ctx, pingTimeout)
+		defer cancel()
+		err := w.ping(ctx)
+		if err == nil {
+			return w
+		}
+		log.Println("Scraper worker failed health check:", err)
+		w.stop()
+	}
+	if p.ctx.Err() != nil {
+		return nil
+	}
+
+	restarted, err := p.start()
+	if err != nil {
+		log.Println("Failed to restart scraper worker:", err)
+		return nil
+	}
+	return restarted
+}
+
+// start launches a worker process
+func (p *WorkerPool) start() (*worker, error) {
+	ctx, cancel := context.WithCancel(p.ctx)
+	cmd := exec.CommandContext(ctx, p.pythonPath, p.scriptPath, "--worker")
+	killProcessGroup(cmd)
+	cmd.WaitDelay = waitDelay
+	cleanup, err := p.sandbox.prepare(cmd, true)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		cancel()
+		cleanup()
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		cancel()
+		cleanup()
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		cancel()
+		cleanup()
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		cancel()
+		cleanup()
+		return nil, err
+	}
+	log.Println("Started scraper worker, pid", cmd.Process.Pid)
+
+	w := &worker{
+		cmd:     cmd,
+		stdin:   stdin,
+		cancel:  cancel,
+		cleanup: cleanup,
+		records: make(chan *Record),
+		exit:    make(chan struct{}),
+	}
+	go logOutput(stderr)
+	go w.read(stdout)
+	return w, nil
+}
+
+// worker is one long-lived scraper process. Only the holder of its pool slot uses it.
+type worker struct {
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	cancel  context.CancelFunc
+	cleanup func()
+	records chan *Record
+	nextID  uint64
+	// skipped counts output lines that were not valid records
+	skipped atomic.Int64
+
+	exit    chan struct{}
+	exitErr error
+	once    sync.Once
+}
+
+// read forwards the worker's records until its output ends, then reaps the process.
+// Malformed lines are logged, counted and skipped.
+func (w *worker) read(stdout io.Reader) {
+	decoder := NewDecoder(stdout)
+records:
+	for {
+		record, err := decoder.Next()
+		var protocolErr *ProtocolError
+		if errors.As(err, &protocolErr) {
+			log.Println("Skipping scraper worker record:", err)
+			w.skipped.Add(1)
+			continue
+		}
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				log.Println("Decoding scraper worker output failed:", err)
+			}
+			break
+		}
+		select {
+		case w.records <- record:
+		case <-w.exit:
+			break records
+		}
+	}
+	w.stop()
+	io.Copy(io.Discard, stdout)
+
+	waitErr := w.cmd.Wait()
+	log.Println("Scraper worker exited:", waitErr)
+	processUsage("Scraper worker", w.cmd.ProcessState)
+	w.cleanup()
+}
+
+// exited reports whether the worker process has stopped
+func (w *worker) exited() bool {
+	select {
+	case <-w.exit:
+		return true
+	default:
+		return false
+	}
+}
+
+// stop kills the worker and every process it started
+func (w *worker) stop() {
+	w.once.Do(func() {
+		w.cancel()
+		close(w.exit)
+	})
+}
+
+// send writes a request to the worker and returns its id
+func (w *worker) send(requestType string, data *ScrapeRequest) (uint64, error) {
+	w.nextID++
+	request := WorkerRequest{Version: ProtocolVersion, ID: w.nextID, Type: requestType, Data: data}
+	line, err := json.Marshal(request)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := w.stdin.Write(append(line, '\n')); err != nil {
+		return 0, fmt.Errorf("writing to scraper worker: %v", err)
+	}
+	return request.ID, nil
+}
+
+// receive returns the next record answering the request, skipping records of requests
+// that were abandoned earlier
+func (w *worker) receive(ctx context.Context, id uint64) (*Record, error) {
+	for {
+		select {
+		case record := <-w.records:
+			if record.ID == id {
+				return record, nil
+			}
+		case <-w.exit:
+			return nil, errWorkerExited
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// ping checks that the worker still answers
+func (w *worker) ping(ctx context.Context) error {
+	id, err := w.send(RequestPing, nil)
+	if err != nil {
+		return err
+	}
+	for {
+		record, err := w.receive(ctx, id)
+		if err != nil {
+			return err
+		}
+		if record.Type == RecordPong {
+			return nil
+		}
+	}
+}
+
+// scrape sends a scrape request and collects the records answering it, also returned as
+// the raw lines the worker wrote. Records that cannot be decoded are skipped but kept in the
+// raw output; they and the malformed lines read meanwhile are counted in the result.
+func (w *worker) scrape(ctx context.Context, request *ScrapeRequest) (*PlatformResult, []byte, error) {
+	skipped := w.skipped.Load()
+	id, err := w.send(RequestScrape, request)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result := &PlatformResult{}
+	var raw bytes.Buffer
+	for result.Summary == nil && result.Error == "" {
+		record, err := w.receive(ctx, id)
+		if err != nil {
+			return nil, nil, err
+		}
+		if record.Type == RecordPong {
+			continue
+		}
+		if record.Platform != request.Platform {
+			return nil, nil, fmt.Errorf("scraper worker answered for %s instead of %s", record.Platform, request.Platform)
+		}
+		raw.Write(record.line)
+		raw.WriteByte('\n')
+		if err := decodeRecord(record, result, func(platform string, progress ProgressRecord) {
+			log.Printf("scraper: %s progress %d/%d %s\n", platform, progress.Done, progress.Total, progress.Message)
+		}); err != nil {
+			log.Printf("Skipping scraper worker %s record: %v\n", record.Type, err)
+			result.Skipped++
+		}
+	}
+	result.Skipped += int(w.skipped.Load() - skipped)
+	return result, raw.Bytes(), nil
+}
@@ -0,0 +1,221 @@
+package selftest
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/zenha/oliveiras/internal/models"
+	"github.com/zenha/oliveiras/internal/parser"
+	"github.com/zenha/oliveiras/internal/scraper"
+)
+
+// Check modes: fixture runs a parser against a saved page, replay decodes recorded script
+// output and live scrapes for real
+const (
+	ModeFixture = "fixture"
+	ModeReplay  = "replay"
+	ModeLive    = "live"
+)
+
+// listingFields are the listing fields a platform can extract
+var listingFields = []string{"url", "name", "price", "currency", "rating", "reviews", "bed_configuration"}
+
+// requiredFields must be extracted for a check to pass
+var requiredFields = []string{"url", "name", "price"}
+
+// Result is the outcome of checking one platform
+type Result struct {
+	Platform string
+	Mode     string
+	Listings int
+	// Fields are the listing fields found in at least one listing
+	Fields []string
+	// Missing are the listing fields no listing had
+	Missing []string
+	Err     error
+}
+
+// OK reports whether the platform produced listings with every required field
+func (r Result) OK() bool {
+	return r.Err == nil
+}
+
+// TotalFields is the number of listing fields a platform can extract
+func TotalFields() int {
+	return len(listingFields)
+}
+
+// recordings hold recorded scraper output, one file per platform, replayed by the self-test
+//
+//go:embed fixtures/*.jsonl
+var recordings embed.FS
+
+// fixtureQuery is the search the fixture pages were saved and the output recorded for
+var fixtureQuery = models.SearchQuery{StartDate: "2025-01-14", EndDate: "2025-01-16"}
+
+// Fixtures runs the parser of every platform against its bundled search results page, so a
+// parser change that breaks on the saved markup is caught, and replays the recorded scraper
+// output of every platform, so protocol changes are checked without the script. Both go
+// through the validation a scrape uses.
+func Fixtures() []Result {
+	return append(parsePages(), replayRecordings()...)
+}
+
+// parsePages runs each platform's parser over its saved page and validates the listings
+func parsePages() []Result {
+	pages, err := fs.Glob(parser.Fixtures, "fixtures/*_search.html")
+	if err != nil {
+		return []Result{{Mode: ModeFixture, Err: err}}
+	}
+
+	results := []Result{}
+	for _, page := range pages {
+		platform := strings.TrimSuffix(path.Base(page), "_search.html")
+		file, err := parser.Fixtures.Open(page)
+		if err != nil {
+			results = append(results, Result{Platform: platform, Mode: ModeFixture, Err: err})
+			continue
+		}
+		parsed, err := parser.Listings(platform, file, fixtureQuery.StartDate, fixtureQuery.EndDate)
+		file.Close()
+
+		listings := []models.ScrapedListing{}
+		for _, listing := range parsed {
+			normalized, normalizeErr := scraper.NormalizeListing(listing, fixtureQuery)
+			if normalizeErr != nil {
+				err = fmt.Errorf("parsed listing %q rejected: %v", listing.URL, normalizeErr)
+				break
+			}
+			listings = append(listings, normalized)
+		}
+		results = append(results, evaluate(platform, ModeFixture, listings, err))
+	}
+	return results
+}
+
+// replayRecordings replays the recorded scraper output of every platform through the
+// decoding and validation a scrape uses
+func replayRecordings() []Result {
+	files, err := fs.Glob(recordings, "fixtures/*.jsonl")
+	if err != nil {
+		return []Result{{Mode: ModeReplay, Err: err}}
+	}
+
+	results := []Result{}
+	for _, file := range files {
+		platform := strings.TrimSuffix(path.Base(file), ".jsonl")
+		output, err := recordings.Open(file)
+		if err != nil {
+			results = append(results, Result{Platform: platform, Mode: ModeReplay, Err: err})
+			continue
+		}
+		listings, rejected, err := scraper.DecodeListings(output, platform, fixtureQuery)
+		output.Close()
+		if err == nil && rejected > 0 {
+			err = fmt.Errorf("%d recorded listings rejected", rejected)
+		}
+		results = append(results, evaluate(platform, ModeReplay, listings, err))
+	}
+	return results
+}
+
+// Live scrapes a single night a month ahead on every platform as a dry run, so the real
+// scraper is checked end to end without storing listings, recording a run or raising
+// anomaly alerts
+func Live(ctx context.Context, scheduler *scraper.Scheduler) []Result {
+	start := time.Now().AddDate(0, 1, 0)
+	query := models.SearchQuery{
+		StartDate: start.Format("2006-01-02"),
+		EndDate:   start.AddDate(0, 0, 1).Format("2006-01-02"),
+	}
+
+	platforms, err := scheduler.Scrape(ctx, query, nil, scraper.ScrapeOptions{Priority: scraper.PriorityLow, DryRun: true, TriggeredBy: "self-test"})
+	if err != nil {
+		results := []Result{}
+		for _, platform := range scheduler.Platforms() {
+			results = append(results, Result{Platform: platform, Mode: ModeLive, Err: err})
+		}
+		return results
+	}
+
+	results := []Result{}
+	for _, platform := range platforms {
+		results = append(results, evaluate(platform.Platform, ModeLive, platform.Listings, platform.Err))
+	}
+	return results
+}
+
+// Failed returns the results that did not pass
+func Failed(results []Result) []Result {
+	failed := []Result{}
+	for _, result := range results {
+		if !result.OK() {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// evaluate counts the fields extracted from the listings and checks the required ones
+func evaluate(platform, mode string, listings []models.ScrapedListing, err error) Result {
+	result := Result{Platform: platform, Mode: mode, Listings: len(listings), Err: err}
+	if err != nil {
+		return result
+	}
+	if len(listings) == 0 {
+		result.Err = errors.New("no listings extracted")
+		return result
+	}
+
+	for _, field := range listingFields {
+		found := false
+		for _, listing := range listings {
+			if hasField(listing, field) {
+				found = true
+				break
+			}
+		}
+		if found {
+			result.Fields = append(result.Fields, field)
+		} else {
+			result.Missing = append(result.Missing, field)
+		}
+	}
+
+	for _, field := range requiredFields {
+		for _, missing := range result.Missing {
+			if field == missing {
+				result.Err = fmt.Errorf("required field %s not extracted", field)
+				return result
+			}
+		}
+	}
+	return result
+}
+
+// hasField reports whether a listing has a value for the field
+func hasField(listing models.ScrapedListing, field string) bool {
+	switch field {
+	case "url":
+		return listing.URL != ""
+	case "name":
+		return listing.Name != ""
+	case "price":
+		return listing.Price > 0
+	case "currency":
+		return listing.PriceDetails != nil && listing.PriceDetails.Currency != ""
+	case "rating":
+		return listing.Rating > 0
+	case "reviews":
+		return listing.Reviews > 0
+	case "bed_configuration":
+		return listing.BedConfiguration != ""
+	}
+	return false
+}
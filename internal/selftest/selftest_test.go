@@ -0,0 +1,26 @@
+package selftest
+
+import (
+	"testing"
+
+	"github.com/zenha/oliveiras/internal/parser"
+)
+
+func TestFixtures(t *testing.T) {
+	results := Fixtures()
+
+	checked := make(map[string]bool)
+	for _, result := range results {
+		checked[result.Mode+" "+result.Platform] = true
+		if !result.OK() {
+			t.Errorf("%s %s failed: %v", result.Mode, result.Platform, result.Err)
+		}
+	}
+	for _, platform := range []string{parser.PlatformAirbnb, parser.PlatformBooking, parser.PlatformVrbo} {
+		for _, mode := range []string{ModeFixture, ModeReplay} {
+			if !checked[mode+" "+platform] {
+				t.Errorf("no %s check for %s", mode, platform)
+			}
+		}
+	}
+}
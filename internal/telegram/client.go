@@ -5,7 +5,11 @@ import (
 	"encoding/json"
 	"io"
 	"log"
+	"mime/multipart"
 	"net/http"
+	"strconv"
+
+	"github.com/zenha/oliveiras/internal/models"
 )
 
 // Client represents a Telegram bot client
@@ -24,15 +28,72 @@ func NewClient(token string) *Client {
 
 // SendMessage sends a message to a Telegram chat
 func (c *Client) SendMessage(chatID int, text string) error {
-	responseBytes, err := json.Marshal(map[string]interface{}{
+	return c.call("sendMessage", map[string]interface{}{
+		"chat_id": chatID,
+		"text":    text,
+	})
+}
+
+// SendMessageWithButtons sends a message with a single row of inline keyboard buttons
+func (c *Client) SendMessageWithButtons(chatID int, text string, buttons []models.InlineKeyboardButton) error {
+	return c.call("sendMessage", map[string]interface{}{
 		"chat_id": chatID,
 		"text":    text,
+		"reply_markup": map[string]interface{}{
+			"inline_keyboard": [][]models.InlineKeyboardButton{buttons},
+		},
+	})
+}
+
+// AnswerCallbackQuery acknowledges an inline keyboard button press
+func (c *Client) AnswerCallbackQuery(callbackQueryID string) error {
+	return c.call("answerCallbackQuery", map[string]interface{}{
+		"callback_query_id": callbackQueryID,
 	})
+}
+
+// SendDocument uploads a file to a Telegram chat
+func (c *Client) SendDocument(chatID int, filename string, data []byte, caption string) error {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	if err := writer.WriteField("chat_id", strconv.Itoa(chatID)); err != nil {
+		return err
+	}
+	if caption != "" {
+		if err := writer.WriteField("caption", caption); err != nil {
+			return err
+		}
+	}
+	part, err := writer.CreateFormFile("document", filename)
+	if err != nil {
+		return err
+	}
+	if _, err := part.Write(data); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", "https://api.telegram.org/bot"+c.token+"/sendDocument", &body)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	return c.do(req)
+}
+
+// call posts a JSON payload to a Telegram Bot API method
+func (c *Client) call(method string, payload map[string]interface{}) error {
+	responseBytes, err := json.Marshal(payload)
 	if err != nil {
 		return err
 	}
 
-	req, err := http.NewRequest("POST", "https://api.telegram.org/bot"+c.token+"/sendMessage", bytes.NewBuffer(responseBytes))
+	req, err := http.NewRequest("POST", "https://api.telegram.org/bot"+c.token+"/"+method, bytes.NewBuffer(responseBytes))
 	if err != nil {
 		log.Println(err)
 		return err
@@ -40,6 +101,11 @@ func (c *Client) SendMessage(chatID int, text string) error {
 
 	req.Header.Set("Content-Type", "application/json")
 
+	return c.do(req)
+}
+
+// do sends a request to the Bot API and logs the response
+func (c *Client) do(req *http.Request) error {
 	resp, err := c.client.Do(req)
 	if err != nil {
 		return err
@@ -0,0 +1,106 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/zenha/oliveiras/internal/models"
+)
+
+// Webhook receives Telegram updates over HTTP and replies through the client
+type Webhook struct {
+	*Client
+	port string
+
+	mu           sync.Mutex
+	lastUpdateID int
+}
+
+// NewWebhook creates a webhook transport listening on the given port
+func NewWebhook(client *Client, port string) *Webhook {
+	return &Webhook{
+		Client: client,
+		port:   port,
+	}
+}
+
+// Receive serves the /webhook endpoint until ctx is done
+func (wh *Webhook) Receive(ctx context.Context, handle func(context.Context, models.Message) error) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/webhook", func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		log.Println(string(body) + "\n")
+
+		var update models.TelegramUpdate
+		if err := json.Unmarshal(body, &update); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Check if we've already processed this update
+		if !wh.markProcessed(update.UpdateID) {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		message := models.Message{
+			ChatID:   update.Message.Chat.ID,
+			UserID:   update.Message.From.ID,
+			UserName: update.Message.From.FirstName,
+			Text:     update.Message.Text,
+		}
+
+		// Inline keyboard buttons carry a command in their callback data
+		if update.CallbackQuery != nil {
+			if err := wh.AnswerCallbackQuery(update.CallbackQuery.ID); err != nil {
+				log.Println("Failed to answer callback query:", err)
+			}
+			message = models.Message{
+				ChatID:   update.CallbackQuery.Message.Chat.ID,
+				UserID:   update.CallbackQuery.From.ID,
+				UserName: update.CallbackQuery.From.FirstName,
+				Text:     update.CallbackQuery.Data,
+			}
+		}
+
+		if err := handle(ctx, message); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	})
+
+	server := &http.Server{Addr: ":" + wh.port, Handler: mux}
+	go func() {
+		<-ctx.Done()
+		server.Shutdown(context.Background())
+	}()
+
+	log.Printf("Starting server on port %s...\n", wh.port)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// markProcessed records the update ID, returning false if it was already processed
+func (wh *Webhook) markProcessed(updateID int) bool {
+	wh.mu.Lock()
+	defer wh.mu.Unlock()
+
+	if updateID <= wh.lastUpdateID {
+		return false
+	}
+	wh.lastUpdateID = updateID
+	return true
+}
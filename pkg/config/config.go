@@ -1,7 +1,13 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -14,6 +20,47 @@ type Config struct {
 	ScraperPath   string
 	ServerPort    string
 	GeminiKey     string
+	// FreshnessDays is how old stored listings may get before they are stale
+	FreshnessDays int
+	// OptionalPlatforms are the platforms whose missing data neither blocks price suggestions
+	// nor counts as a gap to scrape
+	OptionalPlatforms []string
+	// NativePlatforms are the platforms scraped by fetching their search page and parsing it
+	// in Go instead of running the script
+	NativePlatforms []string
+	// AdminUserIDs are the Telegram users allowed to run admin commands
+	AdminUserIDs []int
+	// ScraperTimeout bounds a single scraper run
+	ScraperTimeout time.Duration
+	// ScraperConcurrency caps how many scrapes run at once
+	ScraperConcurrency int
+	// ScraperWorkers is the size of the long-lived scraper worker pool; zero starts the
+	// script once per scrape instead
+	ScraperWorkers int
+	// PlatformConcurrency caps how many scrapes of each platform run at once
+	PlatformConcurrency map[string]int
+	// ScraperAttempts is how many times a failing platform is scraped before giving up
+	ScraperAttempts int
+	// ScraperRetryDelay is the wait before the first retry, doubled for each further one
+	ScraperRetryDelay time.Duration
+	// ScraperMaxMemoryMB, ScraperMaxCPU, ScraperMaxFiles and ScraperMaxProcesses limit each
+	// scraper process; zero leaves a limit unset
+	ScraperMaxMemoryMB  int
+	ScraperMaxCPU       time.Duration
+	ScraperMaxFiles     int
+	ScraperMaxProcesses int
+	// ScraperWorkDir holds the private working directories of scraper processes
+	ScraperWorkDir string
+	// ScraperEnv names extra environment variables passed to the scraper
+	ScraperEnv []string
+	// CalendarBatchSize is how many calendar stays are queued at once
+	CalendarBatchSize int
+	// SelfTestLive adds a tiny real scrape to the startup self-test
+	SelfTestLive bool
+	// ScrapeCacheAge is how recent a scrape of the same query must be to be reused
+	ScrapeCacheAge time.Duration
+	// ArchiveDir holds the compressed raw output of every scrape
+	ArchiveDir string
 }
 
 // Load loads configuration from environment variables
@@ -22,12 +69,128 @@ func Load() (*Config, error) {
 		return nil, err
 	}
 
+	platformConcurrency, err := getLimits("SCRAPER_PLATFORM_CONCURRENCY")
+	if err != nil {
+		return nil, err
+	}
+
 	return &Config{
-		MongoURI:      os.Getenv("MONGO_ATLAS_URI"),
-		TelegramToken: os.Getenv("ZENHA_TELEGRAM_TOKEN"),
-		PythonPath:    os.Getenv("PYTHON_PATH"),
-		ScraperPath:   os.Getenv("SCRAPER_PATH"),
-		ServerPort:    os.Getenv("SERVER_PORT"),
-		GeminiKey:     os.Getenv("GEMINI_API_KEY"),
+		MongoURI:            os.Getenv("MONGO_ATLAS_URI"),
+		TelegramToken:       os.Getenv("ZENHA_TELEGRAM_TOKEN"),
+		PythonPath:          os.Getenv("PYTHON_PATH"),
+		ScraperPath:         os.Getenv("SCRAPER_PATH"),
+		ServerPort:          os.Getenv("SERVER_PORT"),
+		GeminiKey:           os.Getenv("GEMINI_API_KEY"),
+		FreshnessDays:       getInt("FRESHNESS_DAYS", 3),
+		OptionalPlatforms:   getListDefault("OPTIONAL_PLATFORMS", []string{"vrbo"}),
+		NativePlatforms:     getList("NATIVE_PLATFORMS"),
+		AdminUserIDs:        getIntList("ADMIN_USER_IDS"),
+		ScraperTimeout:      getDuration("SCRAPER_TIMEOUT", 10*time.Minute),
+		ScraperConcurrency:  getInt("SCRAPER_CONCURRENCY", 2),
+		ScraperWorkers:      getInt("SCRAPER_WORKERS", 0),
+		PlatformConcurrency: platformConcurrency,
+		ScraperAttempts:     getInt("SCRAPER_ATTEMPTS", 3),
+		ScraperRetryDelay:   getDuration("SCRAPER_RETRY_DELAY", 30*time.Second),
+		ScrapeCacheAge:      getDuration("SCRAPE_CACHE_AGE", 3*time.Hour),
+		SelfTestLive:        os.Getenv("SELFTEST_LIVE") == "true",
+		CalendarBatchSize:   getInt("CALENDAR_BATCH_SIZE", 7),
+		ScraperMaxMemoryMB:  getInt("SCRAPER_MAX_MEMORY_MB", 2048),
+		ScraperMaxCPU:       getDuration("SCRAPER_MAX_CPU", 15*time.Minute),
+		ScraperMaxFiles:     getInt("SCRAPER_MAX_FILES", 1024),
+		ScraperMaxProcesses: getInt("SCRAPER_MAX_PROCESSES", 512),
+		ScraperWorkDir:      getString("SCRAPER_WORKDIR", filepath.Join(os.TempDir(), "oliveiras-scraper")),
+		ScraperEnv:          getList("SCRAPER_ENV"),
+		ArchiveDir:          getString("ARCHIVE_DIR", "archive"),
 	}, nil
 }
+
+// IsAdmin reports whether the user may run admin commands
+func (c *Config) IsAdmin(userID int) bool {
+	return slices.Contains(c.AdminUserIDs, userID)
+}
+
+// IsOptionalPlatform reports whether a platform's missing data may be skipped
+func (c *Config) IsOptionalPlatform(platform string) bool {
+	return slices.Contains(c.OptionalPlatforms, platform)
+}
+
+// IsNativePlatform reports whether a platform is scraped without the script
+func (c *Config) IsNativePlatform(platform string) bool {
+	return slices.Contains(c.NativePlatforms, platform)
+}
+
+// getInt reads an integer environment variable, falling back to def when unset or invalid
+func getInt(key string, def int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+	return value
+}
+
+// getString reads a string environment variable, falling back to def when unset
+func getString(key, def string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return def
+}
+
+// getDuration reads a duration such as "90s" or "10m", falling back to def when unset or invalid
+func getDuration(key string, def time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return def
+	}
+	return value
+}
+
+// getIntList reads a comma-separated list of integers, skipping invalid entries
+func getIntList(key string) []int {
+	values := []int{}
+	for _, field := range strings.Split(os.Getenv(key), ",") {
+		value, err := strconv.Atoi(strings.TrimSpace(field))
+		if err == nil {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// getList reads a comma-separated list, skipping empty entries
+func getList(key string) []string {
+	values := []string{}
+	for _, field := range strings.Split(os.Getenv(key), ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			values = append(values, field)
+		}
+	}
+	return values
+}
+
+// getListDefault reads a comma-separated list, falling back to def when the variable is unset
+func getListDefault(key string, def []string) []string {
+	if _, ok := os.LookupEnv(key); !ok {
+		return def
+	}
+	return getList(key)
+}
+
+// getLimits reads a comma-separated list of name=limit pairs, skipping entries without a
+// value. A limit that is not a number or is below 1 is an error, as it would block the name
+// forever.
+func getLimits(key string) (map[string]int, error) {
+	values := make(map[string]int)
+	for _, field := range strings.Split(os.Getenv(key), ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(field), "=")
+		if !ok {
+			continue
+		}
+		parsed, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || parsed < 1 {
+			return nil, fmt.Errorf("%s: limit for %s must be a number of at least 1, got %q", key, name, value)
+		}
+		values[strings.TrimSpace(name)] = parsed
+	}
+	return values, nil
+}